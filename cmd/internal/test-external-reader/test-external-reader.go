@@ -27,7 +27,10 @@ import (
 )
 
 func main() {
-	client, err := pkl.NewExternalReaderClient(pkl.WithExternalClientResourceReader(fibReader{}))
+	client, err := pkl.NewExternalReaderClient(
+		pkl.WithExternalClientResourceReader(fibReader{}),
+		pkl.WithExternalClientModuleReader(fibModuleReader{}),
+	)
 	if err != nil {
 		log.Fatalln(err)
 	}
@@ -73,6 +76,29 @@ func (r fibReader) Read(uri url.URL) ([]byte, error) {
 	return []byte(strconv.Itoa(fibonacci(n))), nil
 }
 
+// fibModuleReader reads modules whose `value` is a number of the Fibonacci sequence.
+type fibModuleReader struct {
+	fibReader
+}
+
+var _ pkl.ModuleReader = &fibModuleReader{}
+
+func (r fibModuleReader) Scheme() string {
+	return "fibmodule"
+}
+
+func (r fibModuleReader) IsLocal() bool {
+	return false
+}
+
+func (r fibModuleReader) Read(uri url.URL) (string, error) {
+	value, err := r.fibReader.Read(uri)
+	if err != nil {
+		return "", err
+	}
+	return "value = " + string(value), nil
+}
+
 func fibonacci(n int) int {
 	f0, f1 := 0, 1
 	for range n {
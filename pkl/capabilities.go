@@ -0,0 +1,176 @@
+//===----------------------------------------------------------------------===//
+// Copyright © 2026 Apple Inc. and the Pkl project authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//===----------------------------------------------------------------------===//
+
+package pkl
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/apple/pkl-go/pkl/internal"
+)
+
+// Feature is an option or method that is only supported by some versions of Pkl.
+//
+// Its value names the option or method.
+type Feature string
+
+const (
+	// FeatureHttp is EvaluatorOptions.Http.
+	FeatureHttp Feature = "EvaluatorOptions.Http"
+
+	// FeatureHttpRewrites is Http.Rewrites.
+	FeatureHttpRewrites Feature = "Http.Rewrites"
+
+	// FeatureHttpHeaders is Http.Headers.
+	FeatureHttpHeaders Feature = "Http.Headers"
+
+	// FeatureExternalReaders is EvaluatorOptions.ExternalModuleReaders and
+	// EvaluatorOptions.ExternalResourceReaders.
+	FeatureExternalReaders Feature = "EvaluatorOptions.ExternalModuleReaders/ExternalResourceReaders"
+
+	// FeatureExternalReaderWorkingDir is ExternalReader.WorkingDir.
+	FeatureExternalReaderWorkingDir Feature = "ExternalReader.WorkingDir"
+
+	// FeatureTraceMode is EvaluatorOptions.TraceMode.
+	FeatureTraceMode Feature = "EvaluatorOptions.TraceMode"
+
+	// FeatureOutputBytes is Evaluator.EvaluateOutputBytes and Evaluator.EvaluateOutputFilesBytes.
+	FeatureOutputBytes Feature = "Evaluator.EvaluateOutputBytes/EvaluateOutputFilesBytes"
+
+	// FeatureRequiredPklVersion is EvaluatorOptions.RequiredPklVersion.
+	FeatureRequiredPklVersion Feature = "EvaluatorOptions.RequiredPklVersion"
+)
+
+// featureVersions are the minimum Pkl versions of each feature.
+var featureVersions = map[Feature]*internal.Semver{
+	FeatureHttp:                     internal.PklVersion0_26,
+	FeatureHttpRewrites:             internal.PklVersion0_29,
+	FeatureHttpHeaders:              internal.PklVersion0_32,
+	FeatureExternalReaders:          internal.PklVersion0_27,
+	FeatureExternalReaderWorkingDir: internal.PklVersion0_32,
+	FeatureTraceMode:                internal.PklVersion0_30,
+	FeatureOutputBytes:              internal.PklVersion0_29,
+}
+
+// Capabilities describes what the Pkl backing an EvaluatorManager supports.
+type Capabilities struct {
+	// PklVersion is the version of Pkl.
+	PklVersion string
+
+	// Features are the version-dependent features that are supported, in sorted order.
+	Features []Feature
+}
+
+// Supports tells if feature is supported.
+func (c *Capabilities) Supports(feature Feature) bool {
+	return slices.Contains(c.Features, feature)
+}
+
+func capabilitiesOf(version *internal.Semver) *Capabilities {
+	capabilities := &Capabilities{PklVersion: version.String()}
+	for feature, minimum := range featureVersions {
+		if !version.IsLessThan(minimum) {
+			capabilities.Features = append(capabilities.Features, feature)
+		}
+	}
+	slices.Sort(capabilities.Features)
+	return capabilities
+}
+
+// UnsupportedFeatureError is returned when a feature is used with a version of Pkl that does not
+// support it.
+//
+// Use errors.As to check for it, and its Feature field to tell which feature is missing.
+type UnsupportedFeatureError struct {
+	// Feature is the feature that is not supported.
+	Feature Feature
+
+	// RequiredVersion is the constraint on the Pkl version that the feature requires, for example
+	// ">=0.29.0".
+	RequiredVersion string
+
+	// PklVersion is the version of the running Pkl.
+	PklVersion string
+}
+
+var _ error = (*UnsupportedFeatureError)(nil)
+
+func (r *UnsupportedFeatureError) Error() string {
+	return fmt.Sprintf("%s requires Pkl %s, but Pkl %s is running", r.Feature, r.RequiredVersion, r.PklVersion)
+}
+
+// requireFeature returns an *UnsupportedFeatureError if version does not support feature.
+func requireFeature(version *internal.Semver, feature Feature) error {
+	minimum := featureVersions[feature]
+	if version.IsLessThan(minimum) {
+		return &UnsupportedFeatureError{Feature: feature, RequiredVersion: ">=" + minimum.String(), PklVersion: version.String()}
+	}
+	return nil
+}
+
+// RequirePklVersion requires the version of Pkl to satisfy constraint.
+//
+// The constraint is a comma-separated list of comparisons, for example ">=0.29, <0.33".
+// If the running Pkl does not satisfy it, creating the evaluator fails with an
+// *UnsupportedFeatureError.
+var RequirePklVersion = func(constraint string) func(opts *EvaluatorOptions) {
+	return func(opts *EvaluatorOptions) {
+		opts.RequiredPklVersion = constraint
+	}
+}
+
+// checkVersion validates that version supports every feature that the options use.
+func (e *EvaluatorOptions) checkVersion(version *internal.Semver) error {
+	if e.RequiredPklVersion != "" {
+		constraint, err := internal.ParseVersionConstraint(e.RequiredPklVersion)
+		if err != nil {
+			return err
+		}
+		if !constraint.Matches(version) {
+			return &UnsupportedFeatureError{Feature: FeatureRequiredPklVersion, RequiredVersion: e.RequiredPklVersion, PklVersion: version.String()}
+		}
+	}
+	var features []Feature
+	if e.Http != nil {
+		features = append(features, FeatureHttp)
+		if len(e.Http.Rewrites) > 0 {
+			features = append(features, FeatureHttpRewrites)
+		}
+		if len(e.Http.Headers) > 0 {
+			features = append(features, FeatureHttpHeaders)
+		}
+	}
+	if len(e.ExternalModuleReaders) > 0 || len(e.ExternalResourceReaders) > 0 {
+		features = append(features, FeatureExternalReaders)
+	}
+	for _, readers := range []map[string]ExternalReader{e.ExternalModuleReaders, e.ExternalResourceReaders} {
+		for _, reader := range readers {
+			if reader.WorkingDir != "" {
+				features = append(features, FeatureExternalReaderWorkingDir)
+			}
+		}
+	}
+	if e.TraceMode != "" {
+		features = append(features, FeatureTraceMode)
+	}
+	for _, feature := range features {
+		if err := requireFeature(version, feature); err != nil {
+			return err
+		}
+	}
+	return nil
+}
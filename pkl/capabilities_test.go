@@ -0,0 +1,109 @@
+//===----------------------------------------------------------------------===//
+// Copyright © 2026 Apple Inc. and the Pkl project authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//===----------------------------------------------------------------------===//
+
+package pkl_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/apple/pkl-go/pkl"
+	"github.com/apple/pkl-go/pkl/pkltest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvaluatorManager_Capabilities(t *testing.T) {
+	manager := newServerManager(t, pkltest.NewServer(pkltest.WithPklVersion("0.29.1")))
+	capabilities, err := manager.Capabilities()
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "0.29.1", capabilities.PklVersion)
+	assert.Equal(t, []pkl.Feature{pkl.FeatureOutputBytes, pkl.FeatureExternalReaders, pkl.FeatureHttp, pkl.FeatureHttpRewrites}, capabilities.Features)
+	assert.True(t, capabilities.Supports(pkl.FeatureHttpRewrites))
+	assert.False(t, capabilities.Supports(pkl.FeatureTraceMode))
+}
+
+func TestNewEvaluator_unsupportedFeatures(t *testing.T) {
+	manager := newServerManager(t, pkltest.NewServer(pkltest.WithPklVersion("0.27.0")))
+
+	tests := []struct {
+		name     string
+		opts     func(opts *pkl.EvaluatorOptions)
+		expected string
+	}{
+		{
+			name:     "trace mode",
+			opts:     func(opts *pkl.EvaluatorOptions) { opts.TraceMode = pkl.TracePretty },
+			expected: "EvaluatorOptions.TraceMode requires Pkl >=0.30.0, but Pkl 0.27.0 is running",
+		},
+		{
+			name: "http rewrites",
+			opts: func(opts *pkl.EvaluatorOptions) {
+				opts.Http = &pkl.Http{Rewrites: map[string]string{"https://a/": "https://b/"}}
+			},
+			expected: "Http.Rewrites requires Pkl >=0.29.0, but Pkl 0.27.0 is running",
+		},
+		{
+			name:     "external reader working dir",
+			opts:     pkl.WithExternalResourceReader("foo", pkl.ExternalReader{Executable: "foo", WorkingDir: "/tmp"}),
+			expected: "ExternalReader.WorkingDir requires Pkl >=0.32.0, but Pkl 0.27.0 is running",
+		},
+		{
+			name:     "required version",
+			opts:     pkl.RequirePklVersion(">=0.28, <0.33"),
+			expected: "EvaluatorOptions.RequiredPklVersion requires Pkl >=0.28, <0.33, but Pkl 0.27.0 is running",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := manager.NewEvaluator(context.Background(), test.opts)
+			var unsupportedErr *pkl.UnsupportedFeatureError
+			assert.ErrorAs(t, err, &unsupportedErr)
+			assert.EqualError(t, err, test.expected)
+		})
+	}
+
+	t.Run("supported", func(t *testing.T) {
+		_, err := manager.NewEvaluator(context.Background(), pkl.RequirePklVersion(">=0.27"), func(opts *pkl.EvaluatorOptions) {
+			opts.Http = &pkl.Http{}
+			opts.ExternalModuleReaders = map[string]pkl.ExternalReader{"foo": {Executable: "foo"}}
+		})
+		assert.NoError(t, err)
+	})
+
+	t.Run("invalid constraint", func(t *testing.T) {
+		_, err := manager.NewEvaluator(context.Background(), pkl.RequirePklVersion("~0.27"))
+		assert.EqualError(t, err, `invalid version constraint "~0.27"`)
+	})
+}
+
+func TestEvaluator_EvaluateOutputBytes_unsupported(t *testing.T) {
+	server := pkltest.NewServer(pkltest.WithPklVersion("0.28.0"))
+	manager := newServerManager(t, server)
+
+	ev, err := manager.NewEvaluator(context.Background())
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = ev.EvaluateOutputBytes(context.Background(), pkl.TextSource("foo = 1"))
+	assert.EqualError(t, err, "Evaluator.EvaluateOutputBytes/EvaluateOutputFilesBytes requires Pkl >=0.29.0, but Pkl 0.28.0 is running")
+	_, err = ev.EvaluateOutputFilesBytes(context.Background(), pkl.TextSource("foo = 1"))
+	var unsupportedErr *pkl.UnsupportedFeatureError
+	assert.ErrorAs(t, err, &unsupportedErr)
+	// nothing was sent to Pkl.
+	assert.Equal(t, 0, server.Evaluations())
+}
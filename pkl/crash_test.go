@@ -0,0 +1,162 @@
+//===----------------------------------------------------------------------===//
+// Copyright © 2026 Apple Inc. and the Pkl project authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//===----------------------------------------------------------------------===//
+
+package pkl_test
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/apple/pkl-go/pkl"
+	"github.com/apple/pkl-go/pkl/pkltest"
+	"github.com/stretchr/testify/assert"
+)
+
+// within fails t if f does not return within a few seconds.
+func within(t *testing.T, what string, f func()) {
+	t.Helper()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		f()
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("%s did not return", what)
+	}
+}
+
+func TestEvaluatorManager_crashWithOpenEvaluators(t *testing.T) {
+	manager := newFakePklManager()
+	ev1, err := manager.NewEvaluator(context.Background())
+	if !assert.NoError(t, err) {
+		return
+	}
+	ev2, err := manager.NewEvaluator(context.Background())
+	if !assert.NoError(t, err) {
+		return
+	}
+	hangErr := make(chan error, 1)
+	go func() {
+		_, err := ev1.EvaluateExpressionRaw(context.Background(), pkl.TextSource(""), "hang")
+		hangErr <- err
+	}()
+	within(t, "evaluation", func() {
+		_, err = ev2.EvaluateExpressionRaw(context.Background(), pkl.TextSource(""), "exit:1")
+	})
+	var exitErr *pkl.ProcessExitedError
+	if assert.ErrorAs(t, err, &exitErr) {
+		assert.Equal(t, 3, exitErr.ExitCode)
+		assert.Equal(t, []string{"fake-pkl: exiting"}, exitErr.Stderr)
+	}
+	within(t, "pending evaluation", func() {
+		assert.ErrorAs(t, <-hangErr, &exitErr)
+	})
+	within(t, "Evaluator.Close", func() {
+		assert.NoError(t, ev1.Close())
+		assert.NoError(t, ev2.Close())
+	})
+	within(t, "EvaluatorManager.Close", func() {
+		assert.NoError(t, manager.Close())
+	})
+	assert.True(t, ev1.Closed())
+	assert.True(t, ev2.Closed())
+}
+
+func TestProcessExitedError_pendingEvaluations(t *testing.T) {
+	manager := newFakePklManager()
+	var evaluators []pkl.Evaluator
+	for range 3 {
+		ev, err := manager.NewEvaluator(context.Background())
+		if !assert.NoError(t, err) {
+			return
+		}
+		evaluators = append(evaluators, ev)
+	}
+	pending := make(chan error, 2)
+	for _, ev := range evaluators[:2] {
+		go func() {
+			_, err := ev.EvaluateExpressionRaw(context.Background(), pkl.TextSource(""), "hang")
+			pending <- err
+		}()
+	}
+	within(t, "evaluation", func() {
+		_, _ = evaluators[2].EvaluateExpressionRaw(context.Background(), pkl.TextSource(""), "exit:2")
+	})
+	for range 2 {
+		within(t, "pending evaluation", func() {
+			var exitErr *pkl.ProcessExitedError
+			if assert.ErrorAs(t, <-pending, &exitErr) {
+				assert.Equal(t, 3, exitErr.ExitCode)
+			}
+		})
+	}
+	within(t, "evaluation after the exit", func() {
+		_, err := evaluators[0].EvaluateExpressionRaw(context.Background(), pkl.TextSource(""), "1")
+		assert.ErrorIs(t, err, pkl.ErrEvaluatorClosed)
+	})
+	within(t, "NewEvaluator after the exit", func() {
+		_, err := manager.NewEvaluator(context.Background())
+		assert.ErrorIs(t, err, pkl.ErrManagerClosed)
+	})
+	within(t, "EvaluatorManager.Close", func() {
+		assert.NoError(t, manager.Close())
+	})
+}
+
+func TestSupervisor_evaluateDuringRestarts(t *testing.T) {
+	var restarts atomic.Int64
+	manager := newFakePklManager(
+		pkl.WithPklCommand([]string{os.Args[0], "fake-pkl", "--exit-after=50ms"}),
+		pkl.WithSupervisor(pkl.SupervisorOptions{
+			InitialBackoff: time.Millisecond,
+			Timeout:        time.Second,
+			OnRestart: func(event pkl.RestartEvent) {
+				// a process may also exit before it is fully restarted, in which case the attempt
+				// times out, and is retried.
+				if event.Err == nil {
+					restarts.Add(1)
+				}
+			},
+		}),
+	)
+	defer func() { assert.NoError(t, manager.Close()) }()
+	ev, err := manager.NewEvaluator(context.Background())
+	if !assert.NoError(t, err) {
+		return
+	}
+	// evaluations keep starting while Pkl restarts, instead of waiting on the ones in flight.
+	var wg sync.WaitGroup
+	for i := 0; restarts.Load() < 3; i++ {
+		time.Sleep(50 * time.Microsecond)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			expr := strconv.Itoa(i)
+			out, err := ev.EvaluateExpressionRaw(context.Background(), pkl.TextSource(""), expr)
+			if assert.NoError(t, err) {
+				assert.Equal(t, pkltest.MustEncode(expr), out)
+			}
+		}()
+	}
+	wg.Wait()
+}
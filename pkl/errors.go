@@ -19,13 +19,100 @@ package pkl
 import (
 	"errors"
 	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 )
 
 // EvalError is an error that occurs during the normal evaluation of Pkl code.
 //
 // This means that Pkl evaluation occurred, and the Pkl runtime produced an error.
+//
+// Besides the raw ErrorOutput, the error is parsed into its message, kind, and stack frames.
+// The kind can be tested for with errors.Is, using sentinels like ErrTypeMismatch.
 type EvalError struct {
-	ErrorOutput string
+	// ErrorOutput is the error as rendered by Pkl.
+	ErrorOutput string `json:"errorOutput"`
+
+	// Message is the headline of the error.
+	Message string `json:"message,omitempty"`
+
+	// Details are the lines that follow the headline, if any.
+	//
+	// For example, this is the offending value in a type mismatch.
+	Details string `json:"details,omitempty"`
+
+	// Kind is the kind of the error.
+	Kind EvalErrorKind `json:"kind,omitempty"`
+
+	// Frames are the stack frames of the error, innermost first.
+	Frames []StackFrame `json:"frames,omitempty"`
+
+	// Snippet is the offending source code, taken from the innermost frame that has any.
+	Snippet string `json:"snippet,omitempty"`
+
+	// Hint is text that suggests how to fix the error, if any.
+	Hint string `json:"hint,omitempty"`
+}
+
+// StackFrame is a location within a Pkl program that is part of an EvalError's stack trace.
+type StackFrame struct {
+	// Member is the name of the member being evaluated, for example `foo#bar`.
+	Member string `json:"member,omitempty"`
+
+	// ModuleUri is the URI of the module that contains the frame.
+	ModuleUri string `json:"moduleUri"`
+
+	// Line is the 1-based line number of the frame, or 0 if unknown.
+	Line int `json:"line,omitempty"`
+
+	// Column is the 1-based column number of the frame, or 0 if unknown.
+	Column int `json:"column,omitempty"`
+
+	// Source is the offending line of source code, if any.
+	Source string `json:"source,omitempty"`
+}
+
+// EvalErrorKind categorizes an EvalError.
+type EvalErrorKind string
+
+const (
+	// EvalErrorTypeMismatch is a value whose type does not match its declared type.
+	EvalErrorTypeMismatch EvalErrorKind = "typeMismatch"
+	// EvalErrorConstraintViolation is a value that violates a type constraint.
+	EvalErrorConstraintViolation EvalErrorKind = "constraintViolation"
+	// EvalErrorThrow is an error raised by a `throw` expression.
+	EvalErrorThrow EvalErrorKind = "throw"
+	// EvalErrorCannotFindModule is an import or module that cannot be resolved.
+	EvalErrorCannotFindModule EvalErrorKind = "cannotFindModule"
+	// EvalErrorStackOverflow is an evaluation that exceeded the maximum stack depth.
+	EvalErrorStackOverflow EvalErrorKind = "stackOverflow"
+	// EvalErrorTimeout is an evaluation that exceeded EvaluatorOptions.Timeout.
+	//
+	// Such errors are returned wrapped in a *TimeoutError.
+	EvalErrorTimeout EvalErrorKind = "timeout"
+	// EvalErrorOther is any other kind of error.
+	EvalErrorOther EvalErrorKind = "other"
+)
+
+// Sentinels for each EvalErrorKind, for use with errors.Is.
+var (
+	ErrTypeMismatch        = errors.New("type mismatch")
+	ErrConstraintViolation = errors.New("constraint violation")
+	ErrThrow               = errors.New("throw")
+	ErrCannotFindModule    = errors.New("cannot find module")
+	ErrStackOverflow       = errors.New("stack overflow")
+)
+
+var evalErrorSentinels = map[EvalErrorKind]error{
+	EvalErrorTypeMismatch:        ErrTypeMismatch,
+	EvalErrorConstraintViolation: ErrConstraintViolation,
+	EvalErrorThrow:               ErrThrow,
+	EvalErrorCannotFindModule:    ErrCannotFindModule,
+	EvalErrorStackOverflow:       ErrStackOverflow,
 }
 
 var _ error = (*EvalError)(nil)
@@ -35,15 +122,119 @@ func (r *EvalError) Error() string {
 }
 
 // Is implements the interface expected by errors.Is.
+//
+// It matches any *EvalError, as well as the sentinel of the error's kind.
 func (r *EvalError) Is(err error) bool {
 	if err == nil {
 		return false
 	}
+	if sentinel, ok := evalErrorSentinels[r.Kind]; ok && err == sentinel {
+		return true
+	}
 	var evalError *EvalError
 	ok := errors.As(err, &evalError)
 	return ok
 }
 
+var (
+	ansiEscapePattern = regexp.MustCompile("\x1b\\[[0-9;]*m")
+	sourceLinePattern = regexp.MustCompile(`^(\s*(\d+) \| )(.*)$`)
+	caretLinePattern  = regexp.MustCompile(`^\s*\^+\s*$`)
+	stackFramePattern = regexp.MustCompile(`^at (.*) \((.+?)(?:, line (\d+))?\)$`)
+)
+
+// newEvalError builds an EvalError from the error output of Pkl.
+func newEvalError(errorOutput string) *EvalError {
+	evalError := &EvalError{ErrorOutput: errorOutput, Kind: EvalErrorOther}
+	lines := strings.Split(ansiEscapePattern.ReplaceAllString(errorOutput, ""), "\n")
+	if len(lines) > 0 && strings.Contains(lines[0], "Pkl Error") {
+		lines = lines[1:]
+	}
+	// the first paragraph is the message.
+	var message []string
+	for len(lines) > 0 {
+		line := lines[0]
+		lines = lines[1:]
+		if strings.TrimSpace(line) == "" {
+			if len(message) > 0 {
+				break
+			}
+			continue
+		}
+		message = append(message, line)
+	}
+	if len(message) > 0 {
+		evalError.Message = message[0]
+		evalError.Details = strings.Join(message[1:], "\n")
+	}
+	// then come the stack frames, each optionally preceded by a source line and a caret line.
+	var frame StackFrame
+	var sourcePrefixLen int
+	var hint []string
+	for _, line := range lines {
+		if matches := sourceLinePattern.FindStringSubmatch(line); matches != nil {
+			if frame.Source != "" {
+				// the snippet spans multiple lines.
+				frame.Source += "\n" + matches[3]
+				continue
+			}
+			frame.Line, _ = strconv.Atoi(matches[2])
+			frame.Source = matches[3]
+			sourcePrefixLen = len(matches[1])
+			hint = nil
+			continue
+		}
+		if caretLinePattern.MatchString(line) && frame.Source != "" && frame.Column == 0 {
+			// the carets are aligned with the source line, including its line number prefix.
+			frame.Column = strings.Index(line, "^") - sourcePrefixLen + 1
+			continue
+		}
+		if matches := stackFramePattern.FindStringSubmatch(line); matches != nil {
+			frame.Member = matches[1]
+			frame.ModuleUri = matches[2]
+			if matches[3] != "" {
+				frame.Line, _ = strconv.Atoi(matches[3])
+			}
+			evalError.Frames = append(evalError.Frames, frame)
+			frame = StackFrame{}
+			hint = nil
+			continue
+		}
+		if strings.TrimSpace(line) != "" {
+			hint = append(hint, strings.TrimPrefix(line, "Hint: "))
+		}
+	}
+	evalError.Hint = strings.Join(hint, "\n")
+	for _, f := range evalError.Frames {
+		if f.Source != "" {
+			evalError.Snippet = f.Source
+			break
+		}
+	}
+	evalError.Kind = evalErrorKindOf(evalError)
+	return evalError
+}
+
+func evalErrorKindOf(evalError *EvalError) EvalErrorKind {
+	message := evalError.Message
+	switch {
+	case strings.HasPrefix(message, "Expected value of type"):
+		return EvalErrorTypeMismatch
+	case strings.HasPrefix(message, "Type constraint") && strings.HasSuffix(message, "violated."):
+		return EvalErrorConstraintViolation
+	case strings.HasPrefix(message, "Cannot find module"):
+		return EvalErrorCannotFindModule
+	case strings.HasPrefix(message, "A stack overflow occurred"):
+		return EvalErrorStackOverflow
+	case strings.HasPrefix(message, "Evaluation timed out"):
+		return EvalErrorTimeout
+	case len(evalError.Frames) > 0 && strings.Contains(evalError.Frames[0].Source, "throw("):
+		return EvalErrorThrow
+	default:
+		return EvalErrorOther
+	}
+}
+
 // InternalError indicates that an unexpected error occurred.
 type InternalError struct {
 	err error
@@ -64,3 +255,121 @@ func (r *InternalError) Is(err error) bool {
 	ok := errors.As(err, &internalError)
 	return ok
 }
+
+// CanceledError indicates that an operation was abandoned because its context was done.
+//
+// Err is the error returned by the context, which is either context.Canceled or
+// context.DeadlineExceeded. Use errors.As to check for a *CanceledError.
+type CanceledError struct {
+	Err error
+}
+
+var _ error = (*CanceledError)(nil)
+
+func (r *CanceledError) Error() string {
+	return fmt.Sprintf("evaluation canceled: %v", r.Err)
+}
+
+// Unwrap returns the underlying context error.
+func (r *CanceledError) Unwrap() error {
+	return r.Err
+}
+
+// TimeoutError indicates that an evaluation exceeded EvaluatorOptions.Timeout.
+//
+// Err is the *EvalError reported by Pkl if Pkl stopped the evaluation, or
+// context.DeadlineExceeded if the evaluation was abandoned on the Go side first. Use errors.As to
+// check for a *TimeoutError.
+type TimeoutError struct {
+	Timeout time.Duration
+	Err     error
+}
+
+var _ error = (*TimeoutError)(nil)
+
+func (r *TimeoutError) Error() string {
+	return fmt.Sprintf("evaluation timed out after %s", r.Timeout)
+}
+
+// Unwrap returns the underlying error.
+func (r *TimeoutError) Unwrap() error {
+	return r.Err
+}
+
+// ErrEvaluatorClosed is returned when using an evaluator that is closed, including by
+// evaluations that were in flight when it got closed.
+var ErrEvaluatorClosed = errors.New("evaluator is closed")
+
+// ErrManagerClosed is returned when using an EvaluatorManager that is closed or shutting down,
+// including by evaluations that were in flight when it got closed.
+var ErrManagerClosed = errors.New("EvaluatorManager has been closed")
+
+// ProcessExitedError indicates that the Pkl child process exited unexpectedly.
+//
+// Evaluations that were in flight fail with this error, unless the manager is supervised and
+// restarts Pkl.
+// Unlike an *EvalError, this is not caused by the evaluated Pkl code, and retrying with a new
+// EvaluatorManager may succeed.
+//
+// Use errors.As to check for it.
+type ProcessExitedError struct {
+	// ExitCode is the exit code of the process, or -1 if it was killed by a signal.
+	ExitCode int
+
+	// Signal is the signal that killed the process, if any.
+	Signal os.Signal
+
+	// Pid is the process ID.
+	Pid int
+
+	// Uptime is how long the process ran for.
+	Uptime time.Duration
+
+	// Stderr is the last lines that the process wrote to its standard error.
+	Stderr []string
+
+	// Err is the error returned when waiting for the process, typically an *exec.ExitError.
+	Err error
+}
+
+var _ error = (*ProcessExitedError)(nil)
+
+func (r *ProcessExitedError) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "pkl process %d exited", r.Pid)
+	if r.Signal != nil {
+		fmt.Fprintf(&sb, " with signal %s", r.Signal)
+	} else {
+		fmt.Fprintf(&sb, " with code %d", r.ExitCode)
+	}
+	fmt.Fprintf(&sb, " after %s", r.Uptime.Round(time.Millisecond))
+	if len(r.Stderr) > 0 {
+		sb.WriteString("; stderr:\n")
+		sb.WriteString(strings.Join(r.Stderr, "\n"))
+	}
+	return sb.String()
+}
+
+// Unwrap returns the underlying error.
+func (r *ProcessExitedError) Unwrap() error {
+	return r.Err
+}
+
+// newProcessExitedError describes the exit of the process proc, where err is the result of
+// waiting for it.
+func newProcessExitedError(proc *pklProcess, err error) *ProcessExitedError {
+	ret := &ProcessExitedError{
+		ExitCode: -1,
+		Pid:      proc.cmd.Process.Pid,
+		Uptime:   time.Since(proc.started),
+		Stderr:   proc.stderr.lines(),
+		Err:      err,
+	}
+	if state := proc.cmd.ProcessState; state != nil {
+		ret.ExitCode = state.ExitCode()
+		if status, ok := state.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			ret.Signal = status.Signal()
+		}
+	}
+	return ret
+}
@@ -0,0 +1,126 @@
+//===----------------------------------------------------------------------===//
+// Copyright © 2026 Apple Inc. and the Pkl project authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//===----------------------------------------------------------------------===//
+
+package pkl
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/apple/pkl-go/pkl/internal/msgapi"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewEvalError(t *testing.T) {
+	t.Run("type mismatch", func(t *testing.T) {
+		err := newEvalError(`–– Pkl Error ––
+Expected value of type ` + "`Int`" + `, but got type ` + "`String`" + `.
+Value: "hello"
+
+1 | foo: Int = "hello"
+               ^^^^^^^
+at text#foo (repl:text)
+
+3 | bar = foo
+          ^^^
+at foo#bar (file:///tmp/foo.pkl, line 3)
+`)
+		assert.Equal(t, "Expected value of type `Int`, but got type `String`.", err.Message)
+		assert.Equal(t, `Value: "hello"`, err.Details)
+		assert.Equal(t, EvalErrorTypeMismatch, err.Kind)
+		assert.Equal(t, []StackFrame{
+			{Member: "text#foo", ModuleUri: "repl:text", Line: 1, Column: 12, Source: `foo: Int = "hello"`},
+			{Member: "foo#bar", ModuleUri: "file:///tmp/foo.pkl", Line: 3, Column: 7, Source: "bar = foo"},
+		}, err.Frames)
+		assert.Equal(t, `foo: Int = "hello"`, err.Snippet)
+		assert.ErrorIs(t, err, ErrTypeMismatch)
+		assert.NotErrorIs(t, err, ErrThrow)
+		assert.ErrorIs(t, err, &EvalError{})
+	})
+
+	t.Run("throw with hint", func(t *testing.T) {
+		err := newEvalError("\x1b[31m–– Pkl Error ––\x1b[m\nuh oh\n\n10 | foo = throw(\"uh oh\")\n           ^^^^^^^^^^^^^^\nat foo#foo (file:///tmp/foo.pkl)\n\nTry something else.\n")
+		assert.Equal(t, "uh oh", err.Message)
+		assert.Equal(t, EvalErrorThrow, err.Kind)
+		assert.Equal(t, 7, err.Frames[0].Column)
+		assert.Equal(t, 10, err.Frames[0].Line)
+		assert.Equal(t, "Try something else.", err.Hint)
+		assert.True(t, errors.Is(err, ErrThrow))
+	})
+
+	t.Run("json", func(t *testing.T) {
+		err := newEvalError("–– Pkl Error ––\nCannot find module `file:///tmp/missing.pkl`.\n\n1 | import \"missing.pkl\"\n           ^^^^^^^^^^^^^\nat text (repl:text)\n")
+		assert.ErrorIs(t, err, ErrCannotFindModule)
+		b, jsonErr := json.Marshal(err)
+		assert.NoError(t, jsonErr)
+		var decoded EvalError
+		assert.NoError(t, json.Unmarshal(b, &decoded))
+		assert.Equal(t, err, &decoded)
+	})
+
+	t.Run("unstructured output", func(t *testing.T) {
+		err := newEvalError("something went wrong")
+		assert.Equal(t, "something went wrong", err.Message)
+		assert.Equal(t, EvalErrorOther, err.Kind)
+		assert.Empty(t, err.Frames)
+		assert.Equal(t, "something went wrong", err.Error())
+	})
+}
+
+func TestLineTail(t *testing.T) {
+	tail := &lineTail{max: 2}
+	_, _ = tail.Write([]byte("one\ntwo\r\nthr"))
+	assert.Equal(t, []string{"two", "thr"}, tail.lines())
+	_, _ = tail.Write([]byte("ee\nfour\n"))
+	assert.Equal(t, []string{"three", "four"}, tail.lines())
+}
+
+func TestProcessExitedError(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	e := &execEvaluator{
+		in:         make(chan msgapi.IncomingMessage),
+		out:        make(chan msgapi.OutgoingMessage),
+		closed:     make(chan error),
+		pklCommand: []string{"sh", "-c", `echo "starting" >&2; echo "out of memory" >&2; exit 3`, "sh"},
+	}
+	if !assert.NoError(t, e.init()) {
+		return
+	}
+	var err error
+	select {
+	case err = <-e.closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("process did not exit")
+	}
+	wrapped := fmt.Errorf("evaluation failed: %w", err)
+	var exitErr *ProcessExitedError
+	if !assert.ErrorAs(t, wrapped, &exitErr) {
+		return
+	}
+	assert.NotErrorIs(t, wrapped, &EvalError{})
+	assert.Equal(t, 3, exitErr.ExitCode)
+	assert.Nil(t, exitErr.Signal)
+	assert.NotZero(t, exitErr.Pid)
+	assert.Equal(t, []string{"starting", "out of memory"}, exitErr.Stderr)
+	assert.Contains(t, exitErr.Error(), "with code 3")
+	assert.Contains(t, exitErr.Error(), "out of memory")
+}
@@ -0,0 +1,386 @@
+//===----------------------------------------------------------------------===//
+// Copyright © 2026 Apple Inc. and the Pkl project authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//===----------------------------------------------------------------------===//
+
+package pkl
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apple/pkl-go/pkl/internal"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// EvaluationCacheOptions is the set of options available to control an EvaluationCache.
+type EvaluationCacheOptions struct {
+	// TTL is how long an entry stays valid after it is stored.
+	//
+	// If zero, entries only expire when they are purged or invalidated.
+	TTL time.Duration
+
+	// MaxEntries is the maximum number of entries kept in memory.
+	// When exceeded, the least recently used entry is evicted from memory.
+	//
+	// If zero, the number of entries in memory is unbounded.
+	MaxEntries int
+
+	// Dir is a directory where entries are also stored on disk, so that they survive restarts and
+	// can be shared between processes.
+	//
+	// If empty, entries are only kept in memory.
+	Dir string
+}
+
+// EvaluationCache stores the raw results of evaluations, so that evaluating the same expression
+// on the same module again does not require a round trip to Pkl.
+//
+// Entries are keyed by the module's URI and text, the expression, and a fingerprint of the
+// options of the evaluator that produced them.
+// Only successful evaluations are cached.
+//
+// Entries are discarded when their TTL expires, when they are purged, or when a ModuleReader or
+// ResourceReader that implements ReaderChangeNotifier reports a change to a URI that was read
+// while evaluating them.
+//
+// When evaluating a `file:` module, the modification time and size of its file are also part of
+// the key, so that editing the file does not serve stale results.
+// Beyond that, the cache cannot tell when modules that are read by Pkl itself change (for example,
+// `file:` modules imported by the evaluated module); it is best suited for immutable modules, like
+// those from `package:` URIs.
+//
+// A cache is enabled on an evaluator through WithEvaluationCache, and may be shared by many
+// evaluators.
+// Evaluators with ModuleReaders or ResourceReaders only use the cache if they also set a
+// namespace through WithEvaluationCacheNamespace.
+type EvaluationCache struct {
+	options EvaluationCacheOptions
+
+	// mu guards entries, lru and notifiers.
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+	// notifiers is the set of readers that the cache has subscribed to.
+	notifiers map[ReaderChangeNotifier]struct{}
+}
+
+// ReaderChangeNotifier is an optional interface for a ModuleReader or ResourceReader whose
+// contents can change.
+//
+// It allows an EvaluationCache to discard results that were computed from stale contents.
+type ReaderChangeNotifier interface {
+	// NotifyOnChange registers callback to be called with the URI of every module or resource
+	// whose contents change.
+	NotifyOnChange(callback func(uri url.URL))
+}
+
+type cacheEntry struct {
+	Key       string    `msgpack:"key"`
+	Result    []byte    `msgpack:"result"`
+	Reads     []string  `msgpack:"reads"`
+	ExpiresAt time.Time `msgpack:"expiresAt"`
+}
+
+func (c *cacheEntry) expired() bool {
+	return !c.ExpiresAt.IsZero() && time.Now().After(c.ExpiresAt)
+}
+
+// NewEvaluationCache creates an EvaluationCache.
+//
+// If EvaluationCacheOptions.Dir is set, it is created if it does not exist.
+func NewEvaluationCache(opts EvaluationCacheOptions) (*EvaluationCache, error) {
+	if opts.TTL < 0 || opts.MaxEntries < 0 {
+		return nil, errors.New("evaluation cache options must not be negative")
+	}
+	if opts.Dir != "" {
+		if err := os.MkdirAll(opts.Dir, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create evaluation cache directory: %w", err)
+		}
+	}
+	return &EvaluationCache{
+		options:   opts,
+		entries:   make(map[string]*list.Element),
+		lru:       list.New(),
+		notifiers: make(map[ReaderChangeNotifier]struct{}),
+	}, nil
+}
+
+// WithEvaluationCache enables caching the results of evaluations in cache.
+var WithEvaluationCache = func(cache *EvaluationCache) func(opts *EvaluatorOptions) {
+	return func(opts *EvaluatorOptions) {
+		opts.EvaluationCache = cache
+	}
+}
+
+// WithEvaluationCacheNamespace sets the namespace of the evaluator within its EvaluationCache.
+//
+// See EvaluatorOptions.EvaluationCacheNamespace.
+var WithEvaluationCacheNamespace = func(namespace string) func(opts *EvaluatorOptions) {
+	return func(opts *EvaluatorOptions) {
+		opts.EvaluationCacheNamespace = namespace
+	}
+}
+
+// Purge discards every entry, both in memory and on disk.
+func (c *EvaluationCache) Purge() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]*list.Element)
+	c.lru.Init()
+	return c.walkDisk(func(path string, _ *cacheEntry) bool { return true })
+}
+
+// Invalidate discards every entry whose evaluation read the module or resource at uri.
+func (c *EvaluationCache) Invalidate(uri url.URL) error {
+	u := uri.String()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, elem := range c.entries {
+		if slices.Contains(elem.Value.(*cacheEntry).Reads, u) {
+			c.lru.Remove(elem)
+			delete(c.entries, key)
+		}
+	}
+	return c.walkDisk(func(_ string, entry *cacheEntry) bool {
+		return entry == nil || slices.Contains(entry.Reads, u)
+	})
+}
+
+// subscribe registers the cache for change notifications from the given readers.
+//
+// Readers are registered without holding c.mu, so that they may call back into the cache right
+// away.
+func (c *EvaluationCache) subscribe(resourceReaders []ResourceReader, moduleReaders []ModuleReader) {
+	var readers []any
+	for _, r := range resourceReaders {
+		readers = append(readers, r)
+	}
+	for _, r := range moduleReaders {
+		readers = append(readers, r)
+	}
+	var subscribed []ReaderChangeNotifier
+	c.mu.Lock()
+	for _, r := range readers {
+		notifier, ok := r.(ReaderChangeNotifier)
+		if !ok || !reflect.TypeOf(notifier).Comparable() {
+			continue
+		}
+		if _, exists := c.notifiers[notifier]; exists {
+			continue
+		}
+		c.notifiers[notifier] = empty
+		subscribed = append(subscribed, notifier)
+	}
+	c.mu.Unlock()
+	for _, notifier := range subscribed {
+		notifier.NotifyOnChange(func(uri url.URL) {
+			if err := c.Invalidate(uri); err != nil {
+				internal.Debug("Failed to invalidate evaluation cache entries for %s: %v", uri.String(), err)
+			}
+		})
+	}
+}
+
+func (c *EvaluationCache) get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, exists := c.entries[key]; exists {
+		entry := elem.Value.(*cacheEntry)
+		if !entry.expired() {
+			c.lru.MoveToFront(elem)
+			return entry.Result, true
+		}
+		c.lru.Remove(elem)
+		delete(c.entries, key)
+	}
+	if c.options.Dir == "" {
+		return nil, false
+	}
+	entry, err := readCacheEntry(c.entryPath(key))
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			internal.Debug("Failed to read evaluation cache entry %s: %v", key, err)
+		}
+		return nil, false
+	}
+	if entry.Key != key || entry.expired() {
+		return nil, false
+	}
+	c.storeInMemory(entry)
+	return entry.Result, true
+}
+
+func (c *EvaluationCache) put(key string, result []byte, reads []string) {
+	entry := &cacheEntry{Key: key, Result: result, Reads: reads}
+	if c.options.TTL > 0 {
+		entry.ExpiresAt = time.Now().Add(c.options.TTL)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.storeInMemory(entry)
+	if c.options.Dir != "" {
+		if err := writeCacheEntry(c.entryPath(key), entry); err != nil {
+			internal.Debug("Failed to write evaluation cache entry %s: %v", key, err)
+		}
+	}
+}
+
+// storeInMemory must be called while holding c.mu.
+func (c *EvaluationCache) storeInMemory(entry *cacheEntry) {
+	if elem, exists := c.entries[entry.Key]; exists {
+		c.lru.Remove(elem)
+	}
+	c.entries[entry.Key] = c.lru.PushFront(entry)
+	if c.options.MaxEntries > 0 && c.lru.Len() > c.options.MaxEntries {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).Key)
+	}
+}
+
+const cacheEntryExtension = ".pklcache"
+
+func (c *EvaluationCache) entryPath(key string) string {
+	return filepath.Join(c.options.Dir, key[:2], key+cacheEntryExtension)
+}
+
+// walkDisk removes every entry on disk for which shouldRemove returns true.
+// shouldRemove is called with a nil entry if the entry cannot be read.
+//
+// Must be called while holding c.mu.
+func (c *EvaluationCache) walkDisk(shouldRemove func(path string, entry *cacheEntry) bool) error {
+	if c.options.Dir == "" {
+		return nil
+	}
+	return filepath.WalkDir(c.options.Dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(path, cacheEntryExtension) {
+			return nil
+		}
+		entry, err := readCacheEntry(path)
+		if err != nil {
+			entry = nil
+		}
+		if shouldRemove(path, entry) {
+			if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func readCacheEntry(path string) (*cacheEntry, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var entry cacheEntry
+	if err = msgpack.Unmarshal(b, &entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func writeCacheEntry(path string, entry *cacheEntry) error {
+	b, err := msgpack.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	// write to a temporary file first, so that concurrent readers never observe a partial entry.
+	tmp, err := os.CreateTemp(filepath.Dir(path), "*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if _, err = tmp.Write(b); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// usesEvaluationCache tells if results can be cached.
+//
+// The data served by Go readers cannot be fingerprinted, so they require a namespace.
+func (e *EvaluatorOptions) usesEvaluationCache() bool {
+	if e.EvaluationCache == nil {
+		return false
+	}
+	return e.EvaluationCacheNamespace != "" || (len(e.ModuleReaders) == 0 && len(e.ResourceReaders) == 0)
+}
+
+// fingerprint identifies the options that affect the result of an evaluation.
+func (e *EvaluatorOptions) fingerprint() (string, error) {
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetSortMapKeys(true)
+	if err := enc.Encode(e.toMessage()); err != nil {
+		return "", err
+	}
+	// readers are only identified by their scheme in the message; also distinguish their types,
+	// and the namespace that tells apart readers of the same type.
+	_, _ = fmt.Fprintf(&buf, "\x00%d:%s", len(e.EvaluationCacheNamespace), e.EvaluationCacheNamespace)
+	for _, r := range e.ResourceReaders {
+		_, _ = fmt.Fprintf(&buf, "\x00%T", r)
+	}
+	for _, r := range e.ModuleReaders {
+		_, _ = fmt.Fprintf(&buf, "\x00%T", r)
+	}
+	sum := sha256.Sum256(buf.Bytes())
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// evaluationCacheKey returns the key of the result of evaluating expr on source, or false if the
+// result cannot be cached.
+//
+// The text of a `file:` module without Contents is read by Pkl, so the modification time and size
+// of the file identify the text instead. If the file cannot be found, the result is not cached.
+func evaluationCacheKey(fingerprint string, source *ModuleSource, expr string) (string, bool) {
+	parts := []string{fingerprint, source.Uri.String(), source.Contents, expr}
+	if source.Uri.Scheme == "file" && source.Contents == "" {
+		info, err := os.Stat(filepath.FromSlash(source.Uri.Path))
+		if err != nil {
+			return "", false
+		}
+		parts = append(parts, fmt.Sprintf("%d:%d", info.ModTime().UnixNano(), info.Size()))
+	}
+	h := sha256.New()
+	for _, part := range parts {
+		_, _ = fmt.Fprintf(h, "%d:%s", len(part), part)
+	}
+	return hex.EncodeToString(h.Sum(nil)), true
+}
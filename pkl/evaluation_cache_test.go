@@ -0,0 +1,269 @@
+//===----------------------------------------------------------------------===//
+// Copyright © 2026 Apple Inc. and the Pkl project authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//===----------------------------------------------------------------------===//
+
+package pkl_test
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/apple/pkl-go/pkl"
+	"github.com/apple/pkl-go/pkl/pkltest"
+	"github.com/stretchr/testify/assert"
+)
+
+type changingResourceReader struct {
+	callbacks []func(uri url.URL)
+}
+
+func (r *changingResourceReader) Scheme() string {
+	return "changing"
+}
+
+func (r *changingResourceReader) IsGlobbable() bool {
+	return false
+}
+
+func (r *changingResourceReader) HasHierarchicalUris() bool {
+	return false
+}
+
+func (r *changingResourceReader) ListElements(url.URL) ([]pkl.PathElement, error) {
+	return nil, nil
+}
+
+func (r *changingResourceReader) Read(url.URL) ([]byte, error) {
+	return []byte("contents"), nil
+}
+
+func (r *changingResourceReader) NotifyOnChange(callback func(uri url.URL)) {
+	r.callbacks = append(r.callbacks, callback)
+}
+
+func (r *changingResourceReader) change(uri url.URL) {
+	for _, callback := range r.callbacks {
+		callback(uri)
+	}
+}
+
+var (
+	_ pkl.ResourceReader       = (*changingResourceReader)(nil)
+	_ pkl.ReaderChangeNotifier = (*changingResourceReader)(nil)
+)
+
+// newCachingServer creates a server whose evaluations read `changing:secret`, and evaluate to
+// its contents.
+func newCachingServer() *pkltest.Server {
+	server := pkltest.NewServer()
+	server.HandleEvaluate(func(call *pkltest.Call) ([]byte, error) {
+		contents, err := call.ReadResource("changing:secret")
+		if err != nil {
+			return nil, err
+		}
+		return pkltest.Encode(string(contents))
+	})
+	return server
+}
+
+func TestEvaluationCache(t *testing.T) {
+	server := newCachingServer()
+	manager := newServerManager(t, server)
+
+	cache, err := pkl.NewEvaluationCache(pkl.EvaluationCacheOptions{})
+	if !assert.NoError(t, err) {
+		return
+	}
+	reader := &changingResourceReader{}
+	ev, err := manager.NewEvaluator(context.Background(), pkl.WithEvaluationCache(cache), pkl.WithEvaluationCacheNamespace("test"), pkl.WithResourceReader(reader))
+	if !assert.NoError(t, err) {
+		return
+	}
+	source := pkl.UriSource("package://example.com/foo@1.0.0#/foo.pkl")
+	expected := pkltest.MustEncode("contents")
+
+	out, err := ev.EvaluateExpressionRaw(context.Background(), source, "foo")
+	assert.NoError(t, err)
+	assert.Equal(t, expected, out)
+
+	// served from the cache without a round trip to Pkl.
+	out, err = ev.EvaluateExpressionRaw(context.Background(), source, "foo")
+	assert.NoError(t, err)
+	assert.Equal(t, expected, out)
+	assert.Equal(t, 1, server.Evaluations())
+
+	reader.change(url.URL{Scheme: "changing", Opaque: "secret"})
+	out, err = ev.EvaluateExpressionRaw(context.Background(), source, "foo")
+	assert.NoError(t, err)
+	assert.Equal(t, expected, out)
+	assert.Equal(t, 2, server.Evaluations())
+}
+
+func TestEvaluationCache_readers(t *testing.T) {
+	server := newCachingServer()
+	manager := newServerManager(t, server)
+
+	cache, err := pkl.NewEvaluationCache(pkl.EvaluationCacheOptions{})
+	if !assert.NoError(t, err) {
+		return
+	}
+	// evaluate evaluates the same expression with a new evaluator, and tells if Pkl was asked to.
+	evaluate := func(opts ...func(*pkl.EvaluatorOptions)) bool {
+		ev, err := manager.NewEvaluator(context.Background(), append(opts, pkl.WithEvaluationCache(cache), pkl.WithResourceReader(&changingResourceReader{}))...)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		evaluations := server.Evaluations()
+		_, err = ev.EvaluateExpressionRaw(context.Background(), pkl.TextSource("foo = 1"), "foo")
+		assert.NoError(t, err)
+		return server.Evaluations() > evaluations
+	}
+
+	// readers of the same type and scheme may serve different data, so they need a namespace.
+	assert.True(t, evaluate())
+	assert.True(t, evaluate())
+
+	assert.True(t, evaluate(pkl.WithEvaluationCacheNamespace("first")))
+	assert.True(t, evaluate(pkl.WithEvaluationCacheNamespace("second")))
+	assert.False(t, evaluate(pkl.WithEvaluationCacheNamespace("first")))
+}
+
+func TestEvaluationCache_TTL(t *testing.T) {
+	server := newCachingServer()
+	manager := newServerManager(t, server)
+
+	cache, err := pkl.NewEvaluationCache(pkl.EvaluationCacheOptions{TTL: 10 * time.Millisecond})
+	if !assert.NoError(t, err) {
+		return
+	}
+	ev, err := manager.NewEvaluator(context.Background(), pkl.WithEvaluationCache(cache), pkl.WithEvaluationCacheNamespace("test"), pkl.WithResourceReader(&changingResourceReader{}))
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = ev.EvaluateExpressionRaw(context.Background(), pkl.TextSource("foo = 1"), "foo")
+	assert.NoError(t, err)
+	_, err = ev.EvaluateExpressionRaw(context.Background(), pkl.TextSource("foo = 1"), "foo")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, server.Evaluations())
+	time.Sleep(20 * time.Millisecond)
+	_, err = ev.EvaluateExpressionRaw(context.Background(), pkl.TextSource("foo = 1"), "foo")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, server.Evaluations())
+}
+
+func TestEvaluationCache_Dir(t *testing.T) {
+	server := newCachingServer()
+	manager := newServerManager(t, server)
+
+	dir := t.TempDir()
+	// evaluate evaluates the same expression with a new evaluator, and tells if Pkl was asked to.
+	evaluate := func(cache *pkl.EvaluationCache) bool {
+		ev, err := manager.NewEvaluator(context.Background(), pkl.WithEvaluationCache(cache), pkl.WithEvaluationCacheNamespace("test"), pkl.WithResourceReader(&changingResourceReader{}))
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		evaluations := server.Evaluations()
+		_, err = ev.EvaluateExpressionRaw(context.Background(), pkl.TextSource("foo = 1"), "foo")
+		assert.NoError(t, err)
+		return server.Evaluations() > evaluations
+	}
+	cache, err := pkl.NewEvaluationCache(pkl.EvaluationCacheOptions{Dir: dir})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.True(t, evaluate(cache))
+
+	// a new cache picks up entries written to disk by another.
+	other, err := pkl.NewEvaluationCache(pkl.EvaluationCacheOptions{Dir: dir})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.False(t, evaluate(other))
+
+	assert.NoError(t, other.Invalidate(url.URL{Scheme: "changing", Opaque: "secret"}))
+	assert.True(t, evaluate(other))
+
+	assert.NoError(t, other.Purge())
+	assert.True(t, evaluate(other))
+}
+
+func TestEvaluationCache_file(t *testing.T) {
+	server := pkltest.NewServer()
+	server.HandleEvaluate(func(call *pkltest.Call) ([]byte, error) {
+		return pkltest.Encode(call.Expr)
+	})
+	manager := newServerManager(t, server)
+
+	cache, err := pkl.NewEvaluationCache(pkl.EvaluationCacheOptions{})
+	if !assert.NoError(t, err) {
+		return
+	}
+	ev, err := manager.NewEvaluator(context.Background(), pkl.WithEvaluationCache(cache))
+	if !assert.NoError(t, err) {
+		return
+	}
+	path := filepath.Join(t.TempDir(), "config.pkl")
+	// evaluate evaluates the file, and tells if Pkl was asked to.
+	evaluate := func() bool {
+		evaluations := server.Evaluations()
+		_, err := ev.EvaluateExpressionRaw(context.Background(), pkl.FileSource(path), "foo")
+		assert.NoError(t, err)
+		return server.Evaluations() > evaluations
+	}
+
+	// the file cannot be found, so the result is not cached.
+	assert.True(t, evaluate())
+	assert.True(t, evaluate())
+
+	assert.NoError(t, os.WriteFile(path, []byte("foo = 1"), 0o644))
+	assert.True(t, evaluate())
+	assert.False(t, evaluate())
+
+	assert.NoError(t, os.WriteFile(path, []byte("foo = 22"), 0o644))
+	assert.True(t, evaluate())
+}
+
+// eagerResourceReader reports a change as soon as it is subscribed to.
+type eagerResourceReader struct {
+	changingResourceReader
+}
+
+func (r *eagerResourceReader) NotifyOnChange(callback func(uri url.URL)) {
+	callback(url.URL{Scheme: "changing", Opaque: "secret"})
+}
+
+func TestEvaluationCache_subscribe(t *testing.T) {
+	manager := newServerManager(t, newCachingServer())
+
+	cache, err := pkl.NewEvaluationCache(pkl.EvaluationCacheOptions{})
+	if !assert.NoError(t, err) {
+		return
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, err := manager.NewEvaluator(context.Background(), pkl.WithEvaluationCache(cache), pkl.WithResourceReader(&eagerResourceReader{}))
+		assert.NoError(t, err)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("subscribing to a reader that reports a change right away deadlocked")
+	}
+}
@@ -19,10 +19,12 @@ package pkl
 import (
 	"context"
 	"fmt"
-	"log"
 	"net/url"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/apple/pkl-go/pkl/internal"
 	"github.com/apple/pkl-go/pkl/internal/msgapi"
 )
 
@@ -40,7 +42,7 @@ type Evaluator interface {
 
 	// EvaluateOutputBytes evaluates the `output.bytes` property of the given module.
 	//
-	// Supported on Pkl 0.29 and higher.
+	// Supported on Pkl 0.29 and higher; on lower versions, returns an *UnsupportedFeatureError.
 	EvaluateOutputBytes(ctx context.Context, source *ModuleSource) ([]byte, error)
 
 	// EvaluateOutputValue evaluates the `output.value` property of the given module,
@@ -52,7 +54,7 @@ type Evaluator interface {
 
 	// EvaluateOutputFilesBytes evaluates the `output.files` property of the given module, giving the bytes of each file.
 	//
-	// Supported on Pkl 0.29 and higher.
+	// Supported on Pkl 0.29 and higher; on lower versions, returns an *UnsupportedFeatureError.
 	EvaluateOutputFilesBytes(ctx context.Context, source *ModuleSource) (map[string][]byte, error)
 
 	// EvaluateExpression evaluates the provided expression on the given module source, and writes
@@ -62,6 +64,9 @@ type Evaluator interface {
 	// EvaluateExpressionRaw evaluates the provided module, and returns the underlying value's raw
 	// bytes.
 	//
+	// If ctx is done before evaluation completes, a *CanceledError is returned, and the evaluation
+	// is stopped within Pkl.
+	//
 	// This is a low level API.
 	EvaluateExpressionRaw(ctx context.Context, source *ModuleSource, expr string) ([]byte, error)
 
@@ -73,46 +78,81 @@ type Evaluator interface {
 }
 
 type evaluator struct {
-	evaluatorId     int64
+	// mu guards evaluatorId, inFlight and needsReset, and writes to generation.
+	mu          sync.Mutex
+	evaluatorId int64
+	// generation is the generation of the Pkl process that evaluatorId belongs to.
+	generation      atomic.Int64
+	inFlight        int
+	needsReset      bool
 	logger          Logger
 	manager         *evaluatorManager
 	pendingRequests *sync.Map
-	closed          bool
+	closed          atomicBool
+	options         *EvaluatorOptions
 	resourceReaders []ResourceReader
 	moduleReaders   []ModuleReader
+	// interceptors are the manager's interceptors, followed by the evaluator's.
+	interceptors []Interceptor
+	// stats aggregates the metrics of this evaluator.
+	stats statsCollector
+	// metrics receives the metrics of this evaluator.
+	metrics metricsRecorders
+
+	// cacheFingerprint identifies this evaluator's options within EvaluatorOptions.EvaluationCache.
+	cacheFingerprint string
+	// readsMu guards reads.
+	readsMu sync.Mutex
+	// reads is the set of URIs that Pkl asked this evaluator's readers for.
+	reads map[string]struct{}
 }
 
 var _ Evaluator = (*evaluator)(nil)
 
+// The expressions that back the EvaluateOutput* methods of Evaluator.
+const (
+	exprOutputText       = "output.text"
+	exprOutputBytes      = "output.bytes"
+	exprOutputValue      = "output.value"
+	exprOutputFiles      = "output.files?.toMap()?.mapValues((_, it) -> it.text) ?? Map()"
+	exprOutputFilesBytes = "output.files?.toMap()?.mapValues((_, it) -> it.bytes) ?? Map()"
+)
+
 func (e *evaluator) EvaluateModule(ctx context.Context, source *ModuleSource, out any) error {
 	return e.EvaluateExpression(ctx, source, "", out)
 }
 
 func (e *evaluator) EvaluateOutputText(ctx context.Context, source *ModuleSource) (string, error) {
 	var out string
-	err := e.EvaluateExpression(ctx, source, "output.text", &out)
+	err := e.EvaluateExpression(ctx, source, exprOutputText, &out)
 	return out, err
 }
 
 func (e *evaluator) EvaluateOutputBytes(ctx context.Context, source *ModuleSource) ([]byte, error) {
+	if err := e.requireFeature(FeatureOutputBytes); err != nil {
+		return nil, err
+	}
 	var out []byte
-	err := e.EvaluateExpression(ctx, source, "output.bytes", &out)
+	err := e.EvaluateExpression(ctx, source, exprOutputBytes, &out)
 	return out, err
 }
 
 func (e *evaluator) EvaluateOutputValue(ctx context.Context, source *ModuleSource, out any) error {
-	return e.EvaluateExpression(ctx, source, "output.value", out)
+	return e.EvaluateExpression(ctx, source, exprOutputValue, out)
 }
 
 func (e *evaluator) EvaluateOutputFiles(ctx context.Context, source *ModuleSource) (map[string]string, error) {
 	var out map[string]string
-	err := e.EvaluateExpression(ctx, source, "output.files?.toMap()?.mapValues((_, it) -> it.text) ?? Map()", &out)
+	err := e.EvaluateExpression(ctx, source, exprOutputFiles, &out)
 	return out, err
 }
 
 func (e *evaluator) EvaluateOutputFilesBytes(ctx context.Context, source *ModuleSource) (map[string][]byte, error) {
+	if err := e.requireFeature(FeatureOutputBytes); err != nil {
+		return nil, err
+	}
 	var out map[string][]byte
-	err := e.EvaluateExpression(ctx, source, "output.files?.toMap()?.mapValues((_, it) -> it.bytes) ?? Map()", &out)
+	err := e.EvaluateExpression(ctx, source, exprOutputFilesBytes, &out)
 	return out, err
 }
 
@@ -125,36 +165,224 @@ func (e *evaluator) EvaluateExpression(ctx context.Context, source *ModuleSource
 }
 
 func (e *evaluator) EvaluateExpressionRaw(ctx context.Context, source *ModuleSource, expr string) ([]byte, error) {
+	return chainEvaluate(e.interceptors, e.evaluateCached)(ctx, EvaluateRequest{Source: source, Expr: expr})
+}
+
+// evaluateCached evaluates req, consulting EvaluatorOptions.EvaluationCache if it is set.
+func (e *evaluator) evaluateCached(ctx context.Context, req EvaluateRequest) ([]byte, error) {
+	source, expr := req.Source, req.Expr
 	if e.Closed() {
-		return nil, fmt.Errorf("evaluator is closed")
+		return nil, ErrEvaluatorClosed
+	}
+	if !e.manager.beginEvaluation() {
+		return nil, errShuttingDown
+	}
+	defer e.manager.endEvaluation()
+	cache := e.options.EvaluationCache
+	if cache == nil {
+		return e.evaluateRaw(ctx, source, expr)
+	}
+	key, cacheable := evaluationCacheKey(e.cacheFingerprint, source, expr)
+	if !cacheable {
+		return e.evaluateRaw(ctx, source, expr)
+	}
+	start := time.Now()
+	if result, ok := cache.get(key); ok {
+		e.metrics.RecordEvaluation(EvaluationMetric{Duration: time.Since(start), Bytes: len(result), CacheHit: true})
+		return result, nil
+	}
+	result, err := e.evaluateRaw(ctx, source, expr)
+	if err == nil {
+		// Pkl caches modules and resources within an evaluator, so this result may depend on
+		// anything that was read by earlier evaluations too.
+		cache.put(key, result, e.readUris())
+	}
+	return result, err
+}
+
+// requireFeature returns an *UnsupportedFeatureError if the Pkl backing the evaluator does not
+// support feature.
+func (e *evaluator) requireFeature(feature Feature) error {
+	version, err := e.manager.getVersion()
+	if err != nil {
+		return err
+	}
+	return requireFeature(version, feature)
+}
+
+func (e *evaluator) Stats() Stats {
+	return e.stats.snapshot()
+}
+
+func (e *evaluator) evaluateRaw(ctx context.Context, source *ModuleSource, expr string) (result []byte, err error) {
+	start := time.Now()
+	defer func() {
+		metric := EvaluationMetric{Duration: time.Since(start), Bytes: len(result), Err: err}
+		if err != nil {
+			metric.ErrorKind = errorKindOf(err)
+		}
+		e.metrics.RecordEvaluation(metric)
+	}()
+	if timeout := e.options.Timeout; timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, timeout, &TimeoutError{Timeout: timeout, Err: context.DeadlineExceeded})
+		defer cancel()
 	}
 	requestId := random.Int63()
-	ch := make(chan *msgapi.EvaluateResponse)
-	e.pendingRequests.Store(requestId, ch)
-	interrupted, nevermind := e.manager.interrupted(e.evaluatorId)
+	msg := &msgapi.Evaluate{
+		RequestId:  requestId,
+		ModuleUri:  source.Uri.String(),
+		ModuleText: source.Contents,
+		Expr:       expr,
+	}
+	ch := make(chan *msgapi.EvaluateResponse, 1)
+	e.acquire(&pendingEvaluation{msg: msg, ch: ch})
+	canceled := false
+	defer func() { e.release(canceled) }()
+	e.metrics.RecordPendingRequests(1)
+	defer e.metrics.RecordPendingRequests(-1)
+	interrupted, nevermind := e.manager.interrupted(msg.EvaluatorId)
 	defer nevermind()
-	e.manager.impl.outChan() <- &msgapi.Evaluate{
-		RequestId:   requestId,
-		ModuleUri:   source.Uri.String(),
-		ModuleText:  source.Contents,
-		Expr:        expr,
-		EvaluatorId: e.evaluatorId,
+	// the evaluator may have been closed before its interruption could be received.
+	if e.Closed() {
+		e.pendingRequests.Delete(requestId)
+		return nil, ErrEvaluatorClosed
 	}
+	e.manager.send(ctx, msg)
 	select {
 	case <-ctx.Done():
-		return nil, nil
+		e.pendingRequests.Delete(requestId)
+		canceled = true
+		if timeoutErr, ok := context.Cause(ctx).(*TimeoutError); ok {
+			return nil, timeoutErr
+		}
+		return nil, &CanceledError{Err: ctx.Err()}
 	case err := <-interrupted:
+		if err == nil {
+			err = ErrEvaluatorClosed
+		}
 		return nil, err
+	case <-e.manager.implDone:
+		e.pendingRequests.Delete(requestId)
+		return nil, e.manager.implErr
 	case resp := <-ch:
 		if resp.Error != "" {
-			return nil, &EvalError{ErrorOutput: resp.Error}
+			evalErr := newEvalError(resp.Error)
+			if evalErr.Kind == EvalErrorTimeout {
+				return nil, &TimeoutError{Timeout: e.options.Timeout, Err: evalErr}
+			}
+			return nil, evalErr
 		}
 		return resp.Result, nil
 	}
 }
 
+// acquire registers a new in-flight evaluation, and addresses it to the underlying Pkl evaluator.
+//
+// If the evaluator is being reset or recreated, this blocks until the replacement is ready.
+// Otherwise, if the Pkl process has exited, the evaluation is resent by recreate.
+func (e *evaluator) acquire(pending *pendingEvaluation) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.inFlight++
+	pending.msg.EvaluatorId = e.evaluatorId
+	e.pendingRequests.Store(pending.msg.RequestId, pending)
+}
+
+// release marks the end of an in-flight evaluation.
+//
+// A canceled evaluation may still be running within Pkl. To stop it, the underlying Pkl evaluator
+// is closed and replaced with a new one once no other evaluations are in flight.
+func (e *evaluator) release(canceled bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.inFlight--
+	if canceled {
+		e.needsReset = true
+	}
+	if e.needsReset && e.inFlight == 0 {
+		go e.reset()
+	}
+}
+
+// defaultResetTimeout bounds how long resetting an evaluator may take, if neither the evaluator
+// nor the supervisor of its manager has a timeout.
+const defaultResetTimeout = 30 * time.Second
+
+// reset closes the underlying Pkl evaluator, and transparently replaces it with a new one created
+// with the same options.
+//
+// Evaluations wait for the replacement, so it is bound by the evaluator's timeout, or else by the
+// supervisor's. If it cannot be created in time, the evaluator is closed.
+func (e *evaluator) reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.needsReset || e.inFlight > 0 || e.closed.get() || e.manager.closed.get() {
+		return
+	}
+	e.needsReset = false
+	ctx, cancel := context.WithTimeout(context.Background(), e.resetTimeout())
+	defer cancel()
+	e.manager.send(ctx, &msgapi.CloseEvaluator{EvaluatorId: e.evaluatorId})
+	e.manager.evaluators.Delete(e.evaluatorId)
+	resp, err := e.manager.createEvaluator(ctx, e.options)
+	if err != nil {
+		internal.Debug("Failed to reset evaluator %d: %v", e.evaluatorId, err)
+		e.closed.set(true)
+		return
+	}
+	internal.Debug("Replaced evaluator %d with evaluator %d", e.evaluatorId, resp.EvaluatorId)
+	e.evaluatorId = resp.EvaluatorId
+	e.generation.Store(e.manager.generation.Load())
+	e.manager.evaluators.Store(e.evaluatorId, e)
+}
+
+func (e *evaluator) resetTimeout() time.Duration {
+	if e.options.Timeout > 0 {
+		return e.options.Timeout
+	}
+	if supervisor := e.manager.supervisor; supervisor != nil && supervisor.Timeout > 0 {
+		return supervisor.Timeout
+	}
+	return defaultResetTimeout
+}
+
+// recreate replaces the underlying Pkl evaluator after the Pkl process was restarted, and resends
+// all in-flight evaluations to the replacement.
+//
+// Evaluations are side effect free, so it is safe to send them again.
+func (e *evaluator) recreate(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	generation := e.manager.generation.Load()
+	if e.closed.get() || e.generation.Load() == generation {
+		return nil
+	}
+	resp, err := e.manager.createEvaluator(ctx, e.options)
+	if err != nil {
+		return err
+	}
+	e.manager.evaluators.Delete(e.evaluatorId)
+	e.evaluatorId = resp.EvaluatorId
+	e.generation.Store(generation)
+	e.manager.evaluators.Store(e.evaluatorId, e)
+	e.pendingRequests.Range(func(_, value any) bool {
+		msg := *value.(*pendingEvaluation).msg
+		msg.EvaluatorId = e.evaluatorId
+		e.manager.send(ctx, &msg)
+		return true
+	})
+	return nil
+}
+
 func (e *evaluator) Close() error {
-	if e.closed {
+	if e.closed.get() {
+		return nil
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	// another call may have closed the evaluator while waiting for the lock.
+	if e.closed.get() {
 		return nil
 	}
 	e.manager.closeEvaluator(e)
@@ -162,142 +390,176 @@ func (e *evaluator) Close() error {
 }
 
 func (e *evaluator) Closed() bool {
-	return e.closed
+	return e.closed.get()
 }
 
 func (e *evaluator) handleEvaluateResponse(resp *msgapi.EvaluateResponse) {
-	c, exists := e.pendingRequests.Load(resp.RequestId)
+	if e.generation.Load() != e.manager.generation.Load() {
+		// the Pkl process was restarted after the evaluation was sent, so the response may be an
+		// error about an evaluator that the new process does not know; recreate resends it.
+		internal.Debug("Ignoring a response from before a restart for request id: %d", resp.RequestId)
+		return
+	}
+	c, exists := e.pendingRequests.LoadAndDelete(resp.RequestId)
 	if !exists {
-		log.Default().Printf("warn: received a message for an unknown request id: %d", resp.RequestId)
+		// the request was canceled before its response arrived.
+		internal.Debug("Received a message for an unknown request id: %d", resp.RequestId)
 		return
 	}
-	ch := c.(chan *msgapi.EvaluateResponse)
+	ch := c.(*pendingEvaluation).ch
 	ch <- resp
 	close(ch)
-	e.pendingRequests.Delete(resp.RequestId)
 }
 
-func (e *evaluator) handleLog(resp *msgapi.Log) {
-	switch resp.Level {
-	case 0:
-		e.logger.Trace(resp.Message, resp.FrameUri)
-	case 1:
-		e.logger.Warn(resp.Message, resp.FrameUri)
-	default:
-		// log level beyond 1 is impossible
-		panic(fmt.Sprintf("unknown log level: %d", resp.Level))
-	}
-}
-
-func (e *evaluator) handleReadResource(msg *msgapi.ReadResource) {
-	response := &msgapi.ReadResourceResponse{EvaluatorId: e.evaluatorId, RequestId: msg.RequestId}
-	u, err := url.Parse(msg.Uri)
-	if err != nil {
-		response.Error = fmt.Errorf("internal error: failed to parse resource url: %w", err).Error()
-		e.manager.impl.outChan() <- response
+// recordRead remembers that uri was read through this evaluator's readers, if results are cached.
+func (e *evaluator) recordRead(uri string) {
+	if e.options.EvaluationCache == nil {
 		return
 	}
-	reader := e.findResourceReader(u.Scheme)
-	if reader == nil {
-		response.Error = fmt.Sprintf("No resource reader found for scheme `%s`", u.Scheme)
-		e.manager.impl.outChan() <- response
-		return
-	}
-	contents, err := reader.Read(*u)
-	switch {
-	case err == ResourceNotFound:
-		break
-	case err != nil:
-		response.Error = err.Error()
-	default:
-		response.Contents = &contents
+	e.readsMu.Lock()
+	defer e.readsMu.Unlock()
+	if e.reads == nil {
+		e.reads = make(map[string]struct{})
 	}
-	e.manager.impl.outChan() <- response
+	e.reads[uri] = empty
 }
 
-func (e *evaluator) handleReadModule(msg *msgapi.ReadModule) {
-	response := &msgapi.ReadModuleResponse{EvaluatorId: e.evaluatorId, RequestId: msg.RequestId}
-	u, err := url.Parse(msg.Uri)
-	if err != nil {
-		response.Error = fmt.Errorf("internal error: failed to parse resource url: %w", err).Error()
-		e.manager.impl.outChan() <- response
-		return
-	}
-	reader := e.findModuleReader(u.Scheme)
-	if reader == nil {
-		response.Error = fmt.Sprintf("No module reader found for scheme `%s`", u.Scheme)
-		e.manager.impl.outChan() <- response
-		return
-	}
-	response.Contents, err = reader.Read(*u)
-	if err != nil {
-		response.Error = err.Error()
+func (e *evaluator) readUris() []string {
+	e.readsMu.Lock()
+	defer e.readsMu.Unlock()
+	uris := make([]string, 0, len(e.reads))
+	for uri := range e.reads {
+		uris = append(uris, uri)
 	}
-	e.manager.impl.outChan() <- response
+	return uris
 }
 
-func (e *evaluator) handleListResources(msg *msgapi.ListResources) {
-	response := &msgapi.ListResourcesResponse{
-		EvaluatorId: e.evaluatorId,
-		RequestId:   msg.RequestId,
+// pendingEvaluation is an evaluation that is waiting for its response.
+type pendingEvaluation struct {
+	msg *msgapi.Evaluate
+	ch  chan *msgapi.EvaluateResponse
+}
+
+func (e *evaluator) handleLog(resp *msgapi.Log) {
+	final := func(msg LogMessage) {
+		switch msg.Level {
+		case LogLevelTrace:
+			e.logger.Trace(msg.Message, msg.FrameUri)
+		default:
+			// levels unknown to this version of pkl-go are treated as warnings.
+			e.logger.Warn(msg.Message, msg.FrameUri)
+		}
 	}
+	chainLog(e.interceptors, final)(LogMessage{Level: LogLevel(resp.Level), Message: resp.Message, FrameUri: resp.FrameUri})
+}
 
-	u, err := url.Parse(msg.Uri)
+// dispatchRead runs req through the interceptors, and then through the matching reader.
+func (e *evaluator) dispatchRead(operation ReaderOperation, uri string) ReaderResponse {
+	e.recordRead(uri)
+	u, err := url.Parse(uri)
 	if err != nil {
-		response.Error = fmt.Errorf("internal error: failed to parse resource url: %w", err).Error()
-		e.manager.impl.outChan() <- response
-		return
+		return ReaderResponse{Err: fmt.Errorf("internal error: failed to parse resource url: %w", err)}
 	}
+	start := time.Now()
+	resp := chainReader(e.interceptors, e.read)(ReaderRequest{Operation: operation, Uri: *u})
+	e.metrics.RecordReaderCall(ReaderCallMetric{Scheme: u.Scheme, Operation: operation, Duration: time.Since(start), Err: resp.Err})
+	return resp
+}
 
-	reader := e.findResourceReader(u.Scheme)
-	if reader == nil {
-		response.Error = fmt.Sprintf("No resource reader found for scheme `%s`", u.Scheme)
-		e.manager.impl.outChan() <- response
-		return
+func (e *evaluator) read(req ReaderRequest) ReaderResponse {
+	var reader Reader
+	switch req.Operation {
+	case ReadResource, ListResources:
+		if r := e.findResourceReader(req.Uri.Scheme); r != nil {
+			reader = r
+		} else {
+			return ReaderResponse{Err: fmt.Errorf("No resource reader found for scheme `%s`", req.Uri.Scheme)}
+		}
+	default:
+		if r := e.findModuleReader(req.Uri.Scheme); r != nil {
+			reader = r
+		} else {
+			return ReaderResponse{Err: fmt.Errorf("No module reader found for scheme `%s`", req.Uri.Scheme)}
+		}
 	}
+	switch req.Operation {
+	case ReadResource:
+		contents, err := reader.(ResourceReader).Read(req.Uri)
+		return ReaderResponse{Contents: contents, Err: err}
+	case ReadModule:
+		contents, err := reader.(ModuleReader).Read(req.Uri)
+		return ReaderResponse{Contents: []byte(contents), Err: err}
+	default:
+		pathElements, err := reader.ListElements(req.Uri)
+		return ReaderResponse{PathElements: pathElements, Err: err}
+	}
+}
 
-	pathElements, err := reader.ListElements(*u)
-	if err != nil {
-		response.Error = err.Error()
-	} else {
-		response.PathElements = make([]*msgapi.PathElement, len(pathElements))
-		for i, pe := range pathElements {
-			response.PathElements[i] = &msgapi.PathElement{
-				Name:        pe.Name(),
-				IsDirectory: pe.IsDirectory(),
-			}
+// dispatchReaderCall runs a call from Pkl into a reader on the manager's readerDispatcher.
+func (e *evaluator) dispatchReaderCall(operation ReaderOperation, uri string, toMessage func(result ReaderResponse) msgapi.OutgoingMessage) {
+	e.manager.readers.dispatch(e, operation, uri, toMessage, e.manager.impl.outChan())
+}
+
+func (e *evaluator) handleReadResource(msg *msgapi.ReadResource) {
+	e.dispatchReaderCall(ReadResource, msg.Uri, func(result ReaderResponse) msgapi.OutgoingMessage {
+		response := &msgapi.ReadResourceResponse{EvaluatorId: msg.EvaluatorId, RequestId: msg.RequestId}
+		switch {
+		case result.Err == ResourceNotFound:
+			break
+		case result.Err != nil:
+			response.Error = result.Err.Error()
+		default:
+			response.Contents = &result.Contents
 		}
-	}
+		return response
+	})
+}
 
-	e.manager.impl.outChan() <- response
+func (e *evaluator) handleReadModule(msg *msgapi.ReadModule) {
+	e.dispatchReaderCall(ReadModule, msg.Uri, func(result ReaderResponse) msgapi.OutgoingMessage {
+		response := &msgapi.ReadModuleResponse{EvaluatorId: msg.EvaluatorId, RequestId: msg.RequestId}
+		if result.Err != nil {
+			response.Error = result.Err.Error()
+		} else {
+			response.Contents = string(result.Contents)
+		}
+		return response
+	})
+}
+
+func (e *evaluator) handleListResources(msg *msgapi.ListResources) {
+	e.dispatchReaderCall(ListResources, msg.Uri, func(result ReaderResponse) msgapi.OutgoingMessage {
+		response := &msgapi.ListResourcesResponse{EvaluatorId: msg.EvaluatorId, RequestId: msg.RequestId}
+		if result.Err != nil {
+			response.Error = result.Err.Error()
+		} else {
+			response.PathElements = pathElementsToMessage(result.PathElements)
+		}
+		return response
+	})
 }
 
 func (e *evaluator) handleListModules(msg *msgapi.ListModules) {
-	response := &msgapi.ListModulesResponse{EvaluatorId: e.evaluatorId, RequestId: msg.RequestId}
-	u, err := url.Parse(msg.Uri)
-	if err != nil {
-		response.Error = fmt.Errorf("internal error: failed to parse resource url: %w", err).Error()
-		e.manager.impl.outChan() <- response
-		return
-	}
-	reader := e.findModuleReader(u.Scheme)
-	if reader == nil {
-		response.Error = fmt.Sprintf("No module reader found for scheme `%s`", u.Scheme)
-		e.manager.impl.outChan() <- response
-		return
-	}
-	pathElements, err := reader.ListElements(*u)
-	if err != nil {
-		response.Error = err.Error()
-	} else {
-		for _, pathElement := range pathElements {
-			response.PathElements = append(response.PathElements, &msgapi.PathElement{
-				Name:        pathElement.Name(),
-				IsDirectory: pathElement.IsDirectory(),
-			})
+	e.dispatchReaderCall(ListModules, msg.Uri, func(result ReaderResponse) msgapi.OutgoingMessage {
+		response := &msgapi.ListModulesResponse{EvaluatorId: msg.EvaluatorId, RequestId: msg.RequestId}
+		if result.Err != nil {
+			response.Error = result.Err.Error()
+		} else {
+			response.PathElements = pathElementsToMessage(result.PathElements)
+		}
+		return response
+	})
+}
+
+func pathElementsToMessage(pathElements []PathElement) []*msgapi.PathElement {
+	ret := make([]*msgapi.PathElement, len(pathElements))
+	for i, pe := range pathElements {
+		ret[i] = &msgapi.PathElement{
+			Name:        pe.Name(),
+			IsDirectory: pe.IsDirectory(),
 		}
 	}
-	e.manager.impl.outChan() <- response
+	return ret
 }
 
 func (e *evaluator) findModuleReader(scheme string) ModuleReader {
@@ -0,0 +1,152 @@
+//===----------------------------------------------------------------------===//
+// Copyright © 2026 Apple Inc. and the Pkl project authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//===----------------------------------------------------------------------===//
+
+package pkl
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// EvalRequest is a single evaluation within a call to EvaluateBatch.
+type EvalRequest struct {
+	// Source is the module to evaluate.
+	Source *ModuleSource
+
+	// Expr is the expression to evaluate on the module.
+	//
+	// If empty, the whole module is evaluated, like with EvaluateModule.
+	// Use "output.text", "output.value", or "output.bytes" to evaluate a module's output.
+	Expr string
+
+	// Out, if set, is a pointer that the result is unmarshalled into, like with
+	// EvaluateExpression.
+	Out any
+}
+
+// EvalResult is the result of an EvalRequest.
+type EvalResult struct {
+	// Raw is the raw result of the evaluation.
+	Raw []byte
+
+	// Err is the error that occurred while evaluating the request, or while unmarshalling the
+	// result into the request's Out.
+	Err error
+}
+
+// BatchOptions are the options for a call to EvaluateBatch.
+type BatchOptions struct {
+	// MaxInFlight is the maximum number of requests that are sent to Pkl at once.
+	//
+	// If zero, all requests are sent at once.
+	MaxInFlight int
+}
+
+// WithMaxInFlight limits the number of requests of a batch that are sent to Pkl at once.
+var WithMaxInFlight = func(maxInFlight int) func(opts *BatchOptions) {
+	return func(opts *BatchOptions) {
+		opts.MaxInFlight = maxInFlight
+	}
+}
+
+// BatchError is returned by EvaluateBatch if any of its requests failed.
+//
+// It unwraps to the errors of the failed requests.
+type BatchError struct {
+	// Errors are the errors of the failed requests, keyed by the index of the request.
+	Errors map[int]error
+
+	// Total is the number of requests in the batch.
+	Total int
+}
+
+var _ error = (*BatchError)(nil)
+
+func (r *BatchError) Error() string {
+	var sb strings.Builder
+	_, _ = fmt.Fprintf(&sb, "%d of %d evaluations failed", len(r.Errors), r.Total)
+	for i := range r.Total {
+		if err, ok := r.Errors[i]; ok {
+			_, _ = fmt.Fprintf(&sb, "\n[%d]: %v", i, err)
+		}
+	}
+	return sb.String()
+}
+
+// Unwrap implements the interface expected by errors.Is and errors.As.
+func (r *BatchError) Unwrap() []error {
+	errs := make([]error, 0, len(r.Errors))
+	for i := range r.Total {
+		if err, ok := r.Errors[i]; ok {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// EvaluateBatch evaluates every request with ev, and returns their results in input order.
+//
+// The requests are evaluated concurrently through ev.EvaluateExpressionRaw, up to the limit set
+// with WithMaxInFlight. With an evaluator pool, this spreads the requests over the evaluators of
+// the pool.
+// If any request fails, a *BatchError is returned alongside the results.
+func EvaluateBatch(ctx context.Context, ev Evaluator, requests []EvalRequest, opts ...func(options *BatchOptions)) ([]EvalResult, error) {
+	var o BatchOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	var sem chan struct{}
+	if o.MaxInFlight > 0 {
+		sem = make(chan struct{}, o.MaxInFlight)
+	}
+	results := make([]EvalResult, len(requests))
+	var wg sync.WaitGroup
+	for i, req := range requests {
+		if sem != nil {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				results[i].Err = &CanceledError{Err: ctx.Err()}
+				continue
+			}
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if sem != nil {
+				defer func() { <-sem }()
+			}
+			raw, err := ev.EvaluateExpressionRaw(ctx, req.Source, req.Expr)
+			if err == nil && req.Out != nil {
+				err = Unmarshal(raw, req.Out)
+			}
+			results[i] = EvalResult{Raw: raw, Err: err}
+		}()
+	}
+	wg.Wait()
+	batchErr := &BatchError{Errors: make(map[int]error), Total: len(requests)}
+	for i, result := range results {
+		if result.Err != nil {
+			batchErr.Errors[i] = result.Err
+		}
+	}
+	if len(batchErr.Errors) > 0 {
+		return results, batchErr
+	}
+	return results, nil
+}
@@ -0,0 +1,130 @@
+//===----------------------------------------------------------------------===//
+// Copyright © 2026 Apple Inc. and the Pkl project authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//===----------------------------------------------------------------------===//
+
+package pkl_test
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/apple/pkl-go/pkl"
+	"github.com/apple/pkl-go/pkl/pkltest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvaluateBatch(t *testing.T) {
+	server := pkltest.NewServer()
+	// wait for every request to be in flight, and answer them in reverse order.
+	var inFlight sync.WaitGroup
+	inFlight.Add(3)
+	order := []string{"baz", "bar", "foo"}
+	answered := map[string]chan struct{}{"foo": make(chan struct{}), "bar": make(chan struct{}), "baz": make(chan struct{})}
+	server.HandleEvaluate(func(call *pkltest.Call) ([]byte, error) {
+		inFlight.Done()
+		inFlight.Wait()
+		if i := slices.Index(order, call.Expr); i > 0 {
+			<-answered[order[i-1]]
+		}
+		defer close(answered[call.Expr])
+		switch call.Expr {
+		case "foo":
+			return pkltest.Encode(1)
+		case "bar":
+			return pkltest.Encode(2)
+		default:
+			return nil, pkltest.Errorf("Cannot find property `baz`.")
+		}
+	})
+	manager := newServerManager(t, server)
+
+	ev, err := manager.NewEvaluator(context.Background())
+	if !assert.NoError(t, err) {
+		return
+	}
+	var foo int
+	results, err := pkl.EvaluateBatch(context.Background(), ev, []pkl.EvalRequest{
+		{Source: pkl.TextSource("foo = 1"), Expr: "foo", Out: &foo},
+		{Source: pkl.TextSource("bar = 2"), Expr: "bar"},
+		{Source: pkl.TextSource("foo = 1"), Expr: "baz"},
+	})
+	var batchErr *pkl.BatchError
+	if assert.ErrorAs(t, err, &batchErr) {
+		assert.Equal(t, 3, batchErr.Total)
+		assert.Len(t, batchErr.Errors, 1)
+		assert.Contains(t, batchErr.Errors, 2)
+	}
+	var evalErr *pkl.EvalError
+	assert.ErrorAs(t, err, &evalErr)
+	assert.Equal(t, 1, foo)
+	assert.Equal(t, pkltest.MustEncode(1), results[0].Raw)
+	assert.NoError(t, results[0].Err)
+	assert.Equal(t, pkltest.MustEncode(2), results[1].Raw)
+	assert.NoError(t, results[1].Err)
+	assert.Error(t, results[2].Err)
+}
+
+func TestEvaluateBatch_MaxInFlight(t *testing.T) {
+	server := pkltest.NewServer()
+	var mu sync.Mutex
+	inFlight, peak := 0, 0
+	server.HandleEvaluate(func(call *pkltest.Call) ([]byte, error) {
+		mu.Lock()
+		inFlight++
+		peak = max(peak, inFlight)
+		mu.Unlock()
+		// give other evaluations the chance to arrive in the meantime.
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		return pkltest.Encode(nil)
+	})
+	manager := newServerManager(t, server)
+
+	ev, err := manager.NewEvaluator(context.Background())
+	if !assert.NoError(t, err) {
+		return
+	}
+	requests := make([]pkl.EvalRequest, 5)
+	for i := range requests {
+		requests[i] = pkl.EvalRequest{Source: pkl.TextSource("foo = 1"), Expr: "foo"}
+	}
+	results, err := pkl.EvaluateBatch(context.Background(), ev, requests, pkl.WithMaxInFlight(2))
+	assert.NoError(t, err)
+	assert.Len(t, results, 5)
+	assert.Equal(t, 5, server.Evaluations())
+	mu.Lock()
+	defer mu.Unlock()
+	assert.LessOrEqual(t, peak, 2)
+}
+
+func TestEvaluateBatch_canceled(t *testing.T) {
+	manager := newServerManager(t, pkltest.NewServer())
+
+	ev, err := manager.NewEvaluator(context.Background())
+	if !assert.NoError(t, err) {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	results, err := pkl.EvaluateBatch(ctx, ev, []pkl.EvalRequest{{Source: pkl.TextSource("foo = 1"), Expr: "foo"}}, pkl.WithMaxInFlight(1))
+	assert.ErrorIs(t, err, context.Canceled)
+	var canceledErr *pkl.CanceledError
+	assert.ErrorAs(t, results[0].Err, &canceledErr)
+}
@@ -18,9 +18,7 @@ package pkl
 
 import (
 	"context"
-	"fmt"
 	"net/url"
-	"strings"
 
 	"github.com/apple/pkl-go/pkl/internal"
 )
@@ -38,6 +36,7 @@ func NewEvaluator(ctx context.Context, opts ...func(options *EvaluatorOptions))
 // projectBaseUrl.
 //
 // It is similar to running the `pkl eval` or `pkl test` CLI command with a set `--project-dir`.
+// See EvaluatorManager.NewProjectEvaluator for how the options are determined.
 //
 // When using project dependencies, they must first be resolved using the `pkl project resolve`
 // CLI command.
@@ -57,50 +56,12 @@ func NewProjectEvaluator(ctx context.Context, projectBaseUrl *url.URL, opts ...f
 // If creating multiple evaluators, prefer using EvaluatorManager.NewProjectEvaluator instead,
 // because it lessens the overhead of each successive evaluator.
 func NewProjectEvaluatorWithCommand(ctx context.Context, projectBaseUrl *url.URL, pklCmd []string, opts ...func(options *EvaluatorOptions)) (Evaluator, error) {
-	// enforced by Pkl: `file` URIs must conform to RFC-8089.
-	// Pkl currently throws PklBugException if passing a file URI without a path
-	if projectBaseUrl.Scheme == "file" {
-		if !strings.HasPrefix(projectBaseUrl.Path, "/") {
-			return nil, fmt.Errorf(
-				"projectBaseUrl is an invalid file URI: file URIs must have a path component that starts with `/` (e.g. file:///path/to/project). Got: %q",
-				projectBaseUrl,
-			)
-		}
-	}
 	manager := NewEvaluatorManagerWithCommand(pklCmd)
-	projectEvaluator, err := manager.NewEvaluator(ctx, opts...)
+	ev, err := manager.NewProjectEvaluator(ctx, projectBaseUrl, opts...)
 	if err != nil {
-		return nil, err
-	}
-
-	defer func() {
-		if err := projectEvaluator.Close(); err != nil {
-			internal.Debug("Failed to close project evaluator: %v", err)
+		if cerr := manager.Close(); cerr != nil {
+			internal.Debug("Failed to close manager: %v", cerr)
 		}
-	}()
-
-	projectPath := projectBaseUrl.JoinPath("PklProject")
-	project, err := LoadProjectFromEvaluator(ctx, projectEvaluator, &ModuleSource{Uri: projectPath})
-	if err != nil {
-		return nil, err
-	}
-	version, err := manager.GetVersion()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get version from manager: %w", err)
-	}
-	semver, err := internal.ParseSemver(version)
-	if err != nil {
-		return nil, fmt.Errorf("manager returned an invalid semver string: %s. Parsing error: %w", version, err)
-	}
-	var newOpts []func(options *EvaluatorOptions)
-	if semver.IsLessThan(internal.PklVersion0_32) {
-		newOpts = append(newOpts, WithProjectLegacy(project))
-	} else {
-		newOpts = append(newOpts, WithProject(project))
-	}
-	newOpts = append(newOpts, opts...)
-	ev, err := manager.NewEvaluator(ctx, newOpts...)
-	if err != nil {
 		return nil, err
 	}
 	return &simpleEvaluator{Evaluator: ev, manager: manager}, nil
@@ -19,9 +19,13 @@ package pkl
 import (
 	"context"
 	"errors"
-	"log"
+	"fmt"
 	"net/url"
+	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/apple/pkl-go/pkl/internal"
 	"github.com/apple/pkl-go/pkl/internal/msgapi"
@@ -40,9 +44,21 @@ type EvaluatorManager interface {
 	// If calling into Pkl through the C API, close all existing evaluators.
 	Close() error
 
+	// Shutdown gracefully closes the evaluator manager.
+	//
+	// It stops accepting new evaluators and evaluations, waits for in-flight evaluations to
+	// finish, and then closes the evaluator manager like Close.
+	// If ctx is done before in-flight evaluations finish, they are interrupted, and the context's
+	// error is returned.
+	Shutdown(ctx context.Context) error
+
 	// GetVersion returns the version of Pkl backing this evaluator manager.
 	GetVersion() (string, error)
 
+	// Capabilities reports the version-dependent features supported by the Pkl backing this
+	// evaluator manager.
+	Capabilities() (*Capabilities, error)
+
 	// NewEvaluator constructs an evaluator instance.
 	//
 	// If calling into Pkl as a child process, the first time NewEvaluator is called, this will
@@ -54,10 +70,68 @@ type EvaluatorManager interface {
 	// It loads the project from the `PklProject` and `PklProject.deps.json` files within `projectBaseUrl`.
 	//
 	// It is similar to running the `pkl eval` or `pkl test` CLI command with a set `--project-dir`.
+	// The settings that the project sets in `evaluatorSettings` are applied first, and the provided
+	// opts are applied last, like CLI flags.
+	// To also start out with the defaults of the CLI, like reading the environment variables of the
+	// current process, pass WithCliDefaults.
 	//
 	// When using project dependencies, they must first be resolved using the `pkl project resolve`
 	// CLI command.
 	NewProjectEvaluator(ctx context.Context, projectBaseUrl *url.URL, opts ...func(options *EvaluatorOptions)) (Evaluator, error)
+
+	// Stats returns the statistics of all evaluators created by this manager.
+	Stats() Stats
+}
+
+// EvaluatorManagerOptions is the set of options available to control an EvaluatorManager.
+type EvaluatorManagerOptions struct {
+	// PklCommand is the command used to spawn Pkl.
+	//
+	// The first element is treated as the command to run, and any additional elements are passed
+	// to it as arguments. If empty, the command is determined from the PKL_EXEC environment
+	// variable, falling back to "pkl".
+	PklCommand []string
+
+	// Supervisor enables restarting the Pkl process if it exits unexpectedly.
+	//
+	// If nil, the EvaluatorManager is closed when the Pkl process exits.
+	Supervisor *SupervisorOptions
+
+	// Interceptors wrap the traffic of every evaluator created by the manager.
+	Interceptors []Interceptor
+
+	// MetricsRecorders receive the metrics of every evaluator created by the manager.
+	MetricsRecorders []MetricsRecorder
+
+	// Process configures the Pkl child process.
+	Process ProcessOptions
+
+	// Socket, if set, connects the manager to an already running Pkl server, instead of spawning
+	// a Pkl process.
+	Socket *SocketOptions
+
+	// ReaderDispatch controls how calls from Pkl into the readers of evaluators are run.
+	ReaderDispatch ReaderDispatchOptions
+
+	// Sharding, if set, spreads the evaluators of the manager across several Pkl processes.
+	//
+	// It is ignored by NewEvaluatorManagerWithTransport.
+	Sharding *ShardingOptions
+}
+
+// WithPklCommand sets the command used to spawn Pkl.
+var WithPklCommand = func(pklCommand []string) func(opts *EvaluatorManagerOptions) {
+	return func(opts *EvaluatorManagerOptions) {
+		opts.PklCommand = pklCommand
+	}
+}
+
+// WithShutdownGracePeriod sets how long the Pkl process is given to exit after the manager is
+// closed, before it is killed.
+var WithShutdownGracePeriod = func(gracePeriod time.Duration) func(opts *EvaluatorManagerOptions) {
+	return func(opts *EvaluatorManagerOptions) {
+		opts.Process.ShutdownGracePeriod = gracePeriod
+	}
 }
 
 type evaluatorManager struct {
@@ -68,6 +142,51 @@ type evaluatorManager struct {
 	closed            atomicBool
 	newEvaluatorMutex sync.Mutex
 	initialized       bool
+	supervisor        *SupervisorOptions
+	interceptors      []Interceptor
+	metricsRecorders  []MetricsRecorder
+	stats             statsCollector
+	readers           *readerDispatcher
+	// generation counts how many times the Pkl process has been restarted.
+	generation atomic.Int64
+	// closeMu serializes calls to closeErr.
+	closeMu sync.Mutex
+
+	// implDone is closed once impl stops for good, after which nothing reads its outChan.
+	implDone     chan struct{}
+	implDoneOnce sync.Once
+	// implErr is the reason that impl stopped. It is set before implDone is closed.
+	implErr error
+	// sendMu is held for reading while sending to impl, and for writing once implDone is closed,
+	// so that impl is not deinitialized during a send.
+	sendMu sync.RWMutex
+
+	// drainMu guards draining, active and drained.
+	drainMu sync.Mutex
+	// draining is set once Shutdown is called.
+	draining bool
+	// active is the number of in-flight evaluations.
+	active int
+	// drained is closed once draining, and active reaches 0.
+	drained chan struct{}
+}
+
+// newEvaluatorManager creates an EvaluatorManager on top of impl, and starts listening to it.
+func newEvaluatorManager(impl evaluatorManagerImpl, o EvaluatorManagerOptions) *evaluatorManager {
+	m := &evaluatorManager{
+		impl:              impl,
+		interrupts:        &sync.Map{},
+		evaluators:        &sync.Map{},
+		pendingEvaluators: &sync.Map{},
+		supervisor:        o.Supervisor,
+		interceptors:      o.Interceptors,
+		metricsRecorders:  o.MetricsRecorders,
+		readers:           newReaderDispatcher(o.ReaderDispatch),
+		implDone:          make(chan struct{}),
+	}
+	go m.listen()
+	go m.listenForImplClose()
+	return m
 }
 
 // evaluatorManagerImpl is the underlying implementation of the manager. It defines the logic
@@ -89,13 +208,13 @@ func (m *evaluatorManager) NewEvaluator(ctx context.Context, opts ...func(option
 	m.newEvaluatorMutex.Lock()
 	defer m.newEvaluatorMutex.Unlock()
 	if m.closed.get() {
-		return nil, errors.New("EvaluatorManager has been closed")
+		return nil, ErrManagerClosed
 	}
-	if !m.initialized {
-		if err := m.init(); err != nil {
-			return nil, err
-		}
-		m.initialized = true
+	if m.isDraining() {
+		return nil, errShuttingDown
+	}
+	if err := m.ensureInitialized(); err != nil {
+		return nil, err
 	}
 	version, err := m.getVersion()
 	if err != nil {
@@ -105,57 +224,144 @@ func (m *evaluatorManager) NewEvaluator(ctx context.Context, opts ...func(option
 	if err != nil {
 		return nil, err
 	}
-	var newEvaluatorRequest msgapi.OutgoingMessage
+	// paths can only be checked if Pkl runs on this host.
+	var process *ProcessOptions
+	if exec, ok := m.impl.(*execEvaluator); ok {
+		process = &exec.process
+	}
+	if err = o.validate(process); err != nil {
+		return nil, err
+	}
+	var fingerprint string
+	if !o.usesEvaluationCache() {
+		o.EvaluationCache = nil
+	} else {
+		if fingerprint, err = o.fingerprint(); err != nil {
+			return nil, err
+		}
+		o.EvaluationCache.subscribe(o.ResourceReaders, o.ModuleReaders)
+	}
+	resp, err := m.createEvaluator(ctx, o)
+	if err != nil {
+		return nil, err
+	}
+	ev := &evaluator{
+		evaluatorId:     resp.EvaluatorId,
+		logger:          o.Logger,
+		manager:         m,
+		pendingRequests: &sync.Map{},
+		options:         o,
+		resourceReaders: o.ResourceReaders,
+		moduleReaders:   o.ModuleReaders,
+		interceptors:    append(slices.Clone(m.interceptors), o.Interceptors...),
+
+		cacheFingerprint: fingerprint,
+	}
+	ev.generation.Store(m.generation.Load())
+	ev.metrics = append(metricsRecorders{&ev.stats, &m.stats}, m.metricsRecorders...)
+	ev.metrics = append(ev.metrics, o.MetricsRecorders...)
+	m.evaluators.Store(resp.EvaluatorId, ev)
+	return ev, nil
+}
+
+// ensureInitialized starts Pkl, unless it has already been started.
+//
+// The caller must hold newEvaluatorMutex.
+func (m *evaluatorManager) ensureInitialized() error {
+	if m.initialized {
+		return nil
+	}
+	if err := m.init(); err != nil {
+		return err
+	}
+	m.initialized = true
+	return nil
+}
+
+// warmUp starts Pkl ahead of the first call to NewEvaluator.
+func (m *evaluatorManager) warmUp() error {
+	m.newEvaluatorMutex.Lock()
+	defer m.newEvaluatorMutex.Unlock()
+	if m.closed.get() {
+		return ErrManagerClosed
+	}
+	if err := m.ensureInitialized(); err != nil {
+		return err
+	}
+	_, err := m.getVersion()
+	return err
+}
+
+// pendingEvaluator is a request to create an evaluator that is waiting for its response.
+type pendingEvaluator struct {
+	msg *msgapi.CreateEvaluator
+	ch  chan *msgapi.CreateEvaluatorResponse
+}
+
+// createEvaluator asks Pkl to create a new evaluator with the given options.
+//
+// It returns ErrManagerClosed if the manager is closed while waiting.
+func (m *evaluatorManager) createEvaluator(ctx context.Context, o *EvaluatorOptions) (*msgapi.CreateEvaluatorResponse, error) {
 	requestId := random.Int63()
 	msg := o.toMessage()
 	msg.RequestId = requestId
-	newEvaluatorRequest = msg
-	ch := make(chan *msgapi.CreateEvaluatorResponse)
-	m.pendingEvaluators.Store(requestId, ch)
+	ch := make(chan *msgapi.CreateEvaluatorResponse, 1)
+	m.pendingEvaluators.Store(requestId, &pendingEvaluator{msg: msg, ch: ch})
 	interrupt, nevermind := m.interrupted(0)
 	defer nevermind()
-	go func() {
-		m.impl.outChan() <- newEvaluatorRequest
-	}()
+	go m.send(context.Background(), msg)
 	// sanity check: it's possible that the evaluator has been closed at this point.
 	if m.closed.get() {
-		return nil, nil
+		return nil, ErrManagerClosed
 	}
 	select {
 	case <-ctx.Done():
-		return nil, nil
+		if _, stillPending := m.pendingEvaluators.LoadAndDelete(requestId); !stillPending {
+			// The response raced with the cancellation; close the evaluator that nobody will use.
+			if resp := <-ch; resp.Error == "" {
+				m.send(context.Background(), &msgapi.CloseEvaluator{EvaluatorId: resp.EvaluatorId})
+			}
+		}
+		return nil, &CanceledError{Err: ctx.Err()}
 	case err := <-interrupt:
+		if err == nil {
+			err = ErrManagerClosed
+		}
 		return nil, err
+	case <-m.implDone:
+		return nil, m.implErr
 	case resp := <-ch:
 		if resp.Error != "" {
 			return nil, errors.New(resp.Error)
 		}
-		ev := &evaluator{
-			evaluatorId:     resp.EvaluatorId,
-			logger:          o.Logger,
-			manager:         m,
-			pendingRequests: &sync.Map{},
-			resourceReaders: o.ResourceReaders,
-			moduleReaders:   o.ModuleReaders,
-		}
-		m.evaluators.Store(resp.EvaluatorId, ev)
-		return ev, nil
+		return resp, nil
 	}
 }
 
 func (m *evaluatorManager) NewProjectEvaluator(ctx context.Context, projectBaseUrl *url.URL, opts ...func(options *EvaluatorOptions)) (Evaluator, error) {
-	projectEvaluator, err := NewEvaluator(ctx, opts...)
-	defer func() {
-		cerr := projectEvaluator.Close()
-		if err != nil {
-			err = cerr
-		}
-	}()
+	// enforced by Pkl: `file` URIs must conform to RFC-8089.
+	// Pkl currently throws PklBugException if passing a file URI without a path
+	if projectBaseUrl.Scheme == "file" && !strings.HasPrefix(projectBaseUrl.Path, "/") {
+		return nil, fmt.Errorf(
+			"projectBaseUrl is an invalid file URI: file URIs must have a path component that starts with `/` (e.g. file:///path/to/project). Got: %q",
+			projectBaseUrl,
+		)
+	}
+	// the CLI defaults go first, so that the project's evaluator settings override them.
+	var baseOpts []func(options *EvaluatorOptions)
+	if usesCliDefaults(opts) {
+		baseOpts = append(baseOpts, PreconfiguredOptions)
+	}
+	projectEvaluator, err := m.NewEvaluator(ctx, append(slices.Clone(baseOpts), opts...)...)
 	if err != nil {
 		return nil, err
 	}
-	projectSource := projectBaseUrl.JoinPath("PklProject")
-	project, err := LoadProjectFromEvaluator(ctx, projectEvaluator, &ModuleSource{Uri: projectSource})
+	defer func() {
+		if err := projectEvaluator.Close(); err != nil {
+			internal.Debug("Failed to close project evaluator: %v", err)
+		}
+	}()
+	project, err := LoadProjectFromEvaluator(ctx, projectEvaluator, &ModuleSource{Uri: projectBaseUrl.JoinPath("PklProject")})
 	if err != nil {
 		return nil, err
 	}
@@ -163,20 +369,33 @@ func (m *evaluatorManager) NewProjectEvaluator(ctx context.Context, projectBaseU
 	if err != nil {
 		return nil, err
 	}
-	var newOpts []func(options *EvaluatorOptions)
+	newOpts := baseOpts
 	if version.IsLessThan(internal.PklVersion0_32) {
 		newOpts = append(newOpts, WithProjectLegacy(project))
 	} else {
 		newOpts = append(newOpts, WithProject(project))
 	}
 	newOpts = append(newOpts, opts...)
-	return NewEvaluator(ctx, newOpts...)
+	return m.NewEvaluator(ctx, newOpts...)
+}
+
+// usesCliDefaults tells if opts include WithCliDefaults.
+func usesCliDefaults(opts []func(options *EvaluatorOptions)) bool {
+	o := &EvaluatorOptions{}
+	for _, f := range opts {
+		f(o)
+	}
+	return o.cliDefaults
 }
 
 func (m *evaluatorManager) getVersion() (*internal.Semver, error) {
 	return m.impl.getVersion()
 }
 
+func (m *evaluatorManager) Stats() Stats {
+	return m.stats.snapshot()
+}
+
 func (m *evaluatorManager) GetVersion() (string, error) {
 	version, err := m.getVersion()
 	if err != nil {
@@ -185,14 +404,87 @@ func (m *evaluatorManager) GetVersion() (string, error) {
 	return version.String(), nil
 }
 
+func (m *evaluatorManager) Capabilities() (*Capabilities, error) {
+	version, err := m.getVersion()
+	if err != nil {
+		return nil, err
+	}
+	return capabilitiesOf(version), nil
+}
+
 func (m *evaluatorManager) Close() error {
 	return m.closeErr(nil)
 }
 
+var errShuttingDown = fmt.Errorf("%w: it is shutting down", ErrManagerClosed)
+
+func (m *evaluatorManager) Shutdown(ctx context.Context) error {
+	m.drainMu.Lock()
+	if !m.draining {
+		m.draining = true
+		m.drained = make(chan struct{})
+		if m.active == 0 {
+			close(m.drained)
+		}
+	}
+	drained := m.drained
+	m.drainMu.Unlock()
+	select {
+	case <-drained:
+		return m.Close()
+	case <-ctx.Done():
+		_ = m.Close()
+		return ctx.Err()
+	}
+}
+
+func (m *evaluatorManager) isDraining() bool {
+	m.drainMu.Lock()
+	defer m.drainMu.Unlock()
+	return m.draining
+}
+
+// beginEvaluation registers an in-flight evaluation, so that Shutdown waits for it.
+//
+// It returns false if the manager is shutting down, in which case the evaluation must not start.
+func (m *evaluatorManager) beginEvaluation() bool {
+	m.drainMu.Lock()
+	defer m.drainMu.Unlock()
+	if m.draining {
+		return false
+	}
+	m.active++
+	return true
+}
+
+// endEvaluation marks the end of an evaluation registered with beginEvaluation.
+func (m *evaluatorManager) endEvaluation() {
+	m.drainMu.Lock()
+	defer m.drainMu.Unlock()
+	m.active--
+	if m.draining && m.active == 0 {
+		close(m.drained)
+	}
+}
+
+// load returns the number of in-flight evaluations, and the number of open evaluators.
+func (m *evaluatorManager) load() (active int, evaluators int) {
+	m.drainMu.Lock()
+	active = m.active
+	m.drainMu.Unlock()
+	m.evaluators.Range(func(_, _ any) bool {
+		evaluators++
+		return true
+	})
+	return active, evaluators
+}
+
 func (m *evaluatorManager) getEvaluator(evaluatorId int64) *evaluator {
 	v, exists := m.evaluators.Load(evaluatorId)
 	if !exists {
-		log.Default().Printf("warn: received a message for an unknown evaluator id: %d", evaluatorId)
+		// expected after an evaluator is reset or recreated, for responses to the evaluator that it
+		// replaced.
+		internal.Debug("Ignoring a message for an unknown evaluator id: %d", evaluatorId)
 		return nil
 	}
 	return v.(*evaluator)
@@ -204,49 +496,57 @@ func (m *evaluatorManager) listen() {
 		case *msgapi.EvaluateResponse:
 			ev := m.getEvaluator(msg.EvaluatorId)
 			if ev == nil {
-				return
+				continue
 			}
 			ev.handleEvaluateResponse(msg)
 		case *msgapi.Log:
 			ev := m.getEvaluator(msg.EvaluatorId)
 			if ev == nil {
-				return
+				continue
 			}
 			ev.handleLog(msg)
 		case *msgapi.ReadResource:
 			ev := m.getEvaluator(msg.EvaluatorId)
 			if ev == nil {
-				return
+				continue
 			}
 			ev.handleReadResource(msg)
 		case *msgapi.ReadModule:
 			ev := m.getEvaluator(msg.EvaluatorId)
 			if ev == nil {
-				return
+				continue
 			}
 			ev.handleReadModule(msg)
 		case *msgapi.ListResources:
 			ev := m.getEvaluator(msg.EvaluatorId)
 			if ev == nil {
-				return
+				continue
 			}
 			ev.handleListResources(msg)
 		case *msgapi.ListModules:
 			ev := m.getEvaluator(msg.EvaluatorId)
 			if ev == nil {
-				return
+				continue
 			}
 			ev.handleListModules(msg)
 		case *msgapi.CreateEvaluatorResponse:
-			ch, exists := m.pendingEvaluators.Load(msg.RequestId)
+			ch, exists := m.pendingEvaluators.LoadAndDelete(msg.RequestId)
 			if !exists {
-				log.Default().Printf("warn: received a message for an unknown request id: %d", msg.RequestId)
-				return
+				// NewEvaluator was canceled before the response arrived; nobody will use this evaluator.
+				internal.Debug("Received a message for an unknown request id: %d", msg.RequestId)
+				if msg.Error == "" {
+					m.send(context.Background(), &msgapi.CloseEvaluator{EvaluatorId: msg.EvaluatorId})
+				}
+				continue
 			}
-			cch := ch.(chan *msgapi.CreateEvaluatorResponse)
+			cch := ch.(*pendingEvaluator).ch
 			cch <- msg
 			close(cch)
-			m.pendingEvaluators.Delete(msg.RequestId)
+		case *msgapi.Unknown:
+			// sent by a newer version of Pkl; there is nothing that can be done with it.
+			internal.Debug("Received a message with an unknown code: %#x", msg.Code)
+		default:
+			internal.Debug("Received an unexpected message: %T", msg)
 		}
 	}
 }
@@ -256,9 +556,56 @@ func (m *evaluatorManager) listen() {
 //
 // This method will also be called if EvaluatorManager.Close is explicitly called. But it is safe
 // to call `closeErr` multiple times.
+//
+// If the manager is supervised, the Pkl process is restarted instead.
 func (m *evaluatorManager) listenForImplClose() {
-	err := <-m.impl.closedChan()
-	_ = m.closeErr(err)
+	for err := range m.impl.closedChan() {
+		if m.supervisor == nil || m.closed.get() {
+			m.stopImpl(err)
+			_ = m.closeErr(err)
+			return
+		}
+		if rerr := m.restart(err); rerr != nil {
+			m.stopImpl(rerr)
+			_ = m.closeErr(rerr)
+			return
+		}
+	}
+}
+
+// stopImpl records that impl stopped for good because of err, and unblocks every send to it.
+func (m *evaluatorManager) stopImpl(err error) {
+	m.implDoneOnce.Do(func() {
+		if err == nil {
+			err = ErrManagerClosed
+		}
+		m.implErr = err
+		close(m.implDone)
+	})
+	// wait for the sends that are in progress.
+	m.sendMu.Lock()
+	defer m.sendMu.Unlock()
+}
+
+// send sends msg to Pkl.
+//
+// It reports false if msg could not be sent, because ctx is done, or because impl stopped.
+func (m *evaluatorManager) send(ctx context.Context, msg msgapi.OutgoingMessage) bool {
+	m.sendMu.RLock()
+	defer m.sendMu.RUnlock()
+	select {
+	case <-m.implDone:
+		return false
+	default:
+	}
+	select {
+	case m.impl.outChan() <- msg:
+		return true
+	case <-m.implDone:
+		return false
+	case <-ctx.Done():
+		return false
+	}
 }
 
 // interrupted creates a channel that gets published to when an interruption happens, and also
@@ -270,27 +617,40 @@ func (m *evaluatorManager) listenForImplClose() {
 //   - The underlying pkl process died
 //   - The EvaluatorManager was closed
 //   - The Evaluator was closed
+//
+// The channel holds the first interruption only, and is never closed, so that interruptions can
+// be published without blocking, even while the channel is being cleaned up.
 func (m *evaluatorManager) interrupted(evaluatorId int64) (chan error, func()) {
-	ch := make(chan error)
+	ch := make(chan error, 1)
 	m.interrupts.Store(ch, evaluatorId)
 	return ch, func() {
 		m.interrupts.Delete(ch)
-		close(ch)
+	}
+}
+
+// publishInterrupt publishes err to a channel created by interrupted, unless it already holds an
+// interruption.
+func publishInterrupt(ch chan error, err error) {
+	select {
+	case ch <- err:
+	default:
 	}
 }
 
 // closeEvaluator closes the provided evaluator.
+//
+// Pkl is told to close the evaluator, unless it has stopped.
 func (m *evaluatorManager) closeEvaluator(ev *evaluator) {
 	// if the manager itself is closed, there's nothing to do.
 	if m.closed.get() {
 		return
 	}
-	m.impl.outChan() <- &msgapi.CloseEvaluator{EvaluatorId: ev.evaluatorId}
+	m.send(context.Background(), &msgapi.CloseEvaluator{EvaluatorId: ev.evaluatorId})
 	m.evaluators.Delete(ev.evaluatorId)
-	ev.closed = true
+	ev.closed.set(true)
 	m.interrupts.Range(func(key, value any) bool {
 		if value.(int64) == ev.evaluatorId {
-			key.(chan error) <- nil
+			publishInterrupt(key.(chan error), ErrEvaluatorClosed)
 		}
 		return true
 	})
@@ -298,7 +658,7 @@ func (m *evaluatorManager) closeEvaluator(ev *evaluator) {
 
 func (m *evaluatorManager) interrupt(err error) {
 	m.interrupts.Range(func(ch, _ any) bool {
-		ch.(chan error) <- err
+		publishInterrupt(ch.(chan error), err)
 		return true
 	})
 }
@@ -308,10 +668,15 @@ func (m *evaluatorManager) init() error {
 }
 
 func (m *evaluatorManager) closeErr(e error) error {
+	m.closeMu.Lock()
+	defer m.closeMu.Unlock()
 	if m.closed.get() {
 		return nil
 	}
 	var err error
+	if e == nil {
+		e = ErrManagerClosed
+	}
 	m.interrupt(e)
 	m.evaluators.Range(func(evaluatorId, v any) bool {
 		ev := v.(*evaluator)
@@ -322,6 +687,8 @@ func (m *evaluatorManager) closeErr(e error) error {
 		return true
 	})
 	m.closed.set(true)
+	m.readers.close()
+	m.stopImpl(e)
 	derr := m.impl.deinit()
 	if err != nil {
 		return err
@@ -17,11 +17,14 @@
 package pkl
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
 	"regexp"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -33,7 +36,7 @@ import (
 
 // NewEvaluatorManager creates a new EvaluatorManager.
 func NewEvaluatorManager() EvaluatorManager {
-	return NewEvaluatorManagerWithCommand(nil)
+	return NewEvaluatorManagerWithOptions()
 }
 
 // NewEvaluatorManagerWithCommand creates a new EvaluatorManager using the given pkl command.
@@ -45,33 +48,122 @@ func NewEvaluatorManager() EvaluatorManager {
 //
 //	NewEvaluatorManagerWithCommand([]string{"/opt/bin/pkl"})
 func NewEvaluatorManagerWithCommand(pklCommand []string) EvaluatorManager {
-	m := &evaluatorManager{
-		impl: &execEvaluator{
-			in:          make(chan msgapi.IncomingMessage),
-			out:         make(chan msgapi.OutgoingMessage),
-			closed:      make(chan error),
-			pklCommand:  pklCommand,
-			processDone: make(chan struct{}),
-		},
-		interrupts:        &sync.Map{},
-		evaluators:        &sync.Map{},
-		pendingEvaluators: &sync.Map{},
-	}
-	go m.listen()
-	go m.listenForImplClose()
-	return m
+	return NewEvaluatorManagerWithOptions(WithPklCommand(pklCommand))
+}
+
+// NewEvaluatorManagerWithOptions creates a new EvaluatorManager configured by the given options.
+//
+// For example, the below snippet creates a manager that restarts Pkl if it crashes.
+//
+//	NewEvaluatorManagerWithOptions(WithSupervisor(SupervisorOptions{MaxRestarts: 5}))
+func NewEvaluatorManagerWithOptions(opts ...func(options *EvaluatorManagerOptions)) EvaluatorManager {
+	o := EvaluatorManagerOptions{}
+	for _, f := range opts {
+		f(&o)
+	}
+	newImpl := func() evaluatorManagerImpl {
+		return &execEvaluator{
+			in:         make(chan msgapi.IncomingMessage),
+			out:        make(chan msgapi.OutgoingMessage),
+			closed:     make(chan error),
+			closing:    make(chan struct{}),
+			pklCommand: o.PklCommand,
+			process:    o.Process,
+		}
+	}
+	if o.Socket != nil {
+		socket := *o.Socket
+		newImpl = func() evaluatorManagerImpl {
+			return newTransportEvaluator(&socketTransport{options: socket})
+		}
+		if o.Supervisor == nil {
+			supervisor := defaultSocketSupervisor
+			o.Supervisor = &supervisor
+		}
+	}
+	if o.Sharding != nil {
+		return newShardedEvaluatorManager(newImpl, o)
+	}
+	return newEvaluatorManager(newImpl(), o)
 }
 
 type execEvaluator struct {
-	cmd    *exec.Cmd
 	in     chan msgapi.IncomingMessage
 	out    chan msgapi.OutgoingMessage
 	closed chan error
 	// exited is a flag that indicates evaluator was closed explicitly
-	exited      atomicBool
-	version     *internal.Semver
-	pklCommand  []string
-	processDone chan struct{}
+	exited     atomicBool
+	pklCommand []string
+	process    ProcessOptions
+	// closing is closed once deinit starts.
+	closing chan struct{}
+	// reportMu is held for reading while sending to in or closed, and for writing while closing
+	// them.
+	reportMu sync.RWMutex
+
+	// mu guards current and version.
+	mu      sync.Mutex
+	current *pklProcess
+	version *internal.Semver
+}
+
+// pklProcess is a single run of the `pkl server` child process.
+type pklProcess struct {
+	cmd *exec.Cmd
+	// done is closed once the process has exited.
+	done chan struct{}
+	// replaced is closed once the process has been replaced by a new one.
+	replaced    chan struct{}
+	replaceOnce sync.Once
+	exitOnce    sync.Once
+	started     time.Time
+	// stderr keeps the last lines written to the standard error of the process.
+	stderr *lineTail
+}
+
+// stderrTailLines is the number of lines of standard error kept for ProcessExitedError.
+const stderrTailLines = 20
+
+// lineTail is an io.Writer that keeps the last lines written to it.
+type lineTail struct {
+	mu      sync.Mutex
+	max     int
+	tail    []string
+	partial []byte
+}
+
+var _ io.Writer = (*lineTail)(nil)
+
+func (t *lineTail) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.partial = append(t.partial, p...)
+	for {
+		i := bytes.IndexByte(t.partial, '\n')
+		if i < 0 {
+			break
+		}
+		t.tail = append(t.tail, strings.TrimSuffix(string(t.partial[:i]), "\r"))
+		t.partial = t.partial[i+1:]
+	}
+	if len(t.tail) > t.max {
+		t.tail = slices.Clone(t.tail[len(t.tail)-t.max:])
+	}
+	return len(p), nil
+}
+
+// lines returns the last lines written, including an unterminated last line.
+func (t *lineTail) lines() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	ret := slices.Clone(t.tail)
+	if len(t.partial) > 0 {
+		ret = append(ret, string(t.partial))
+	}
+	if len(ret) > t.max {
+		ret = ret[len(ret)-t.max:]
+	}
+	return ret
 }
 
 func (e *execEvaluator) inChan() chan msgapi.IncomingMessage {
@@ -89,11 +181,15 @@ func (e *execEvaluator) closedChan() chan error {
 var pklVersionRegex = regexp.MustCompile(fmt.Sprintf("Pkl (%s).*", internal.SemverPattern.String()))
 
 func (e *execEvaluator) getVersion() (*internal.Semver, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
 	if e.version != nil {
 		return e.version, nil
 	}
 	cmd, args := e.getCommandAndArgStrings()
 	command := exec.Command(cmd, append(args, "--version")...)
+	command.Env = e.process.environ()
+	command.Dir = e.process.WorkingDir
 	versionCmdOut, err := command.Output()
 	if err != nil {
 		return nil, err
@@ -128,97 +224,195 @@ func (e *execEvaluator) getCommandAndArgStrings() (string, []string) {
 }
 
 func (e *execEvaluator) init() error {
-	e.cmd = e.getStartCommand()
-	e.cmd.Env = os.Environ()
-	e.cmd.Stderr = os.Stderr
-	stdin, err := e.cmd.StdinPipe()
+	proc, err := e.start()
 	if err != nil {
 		return err
 	}
-	stdout, err := e.cmd.StdoutPipe()
+	e.mu.Lock()
+	e.current = proc
+	e.mu.Unlock()
+	return nil
+}
+
+var _ restartableImpl = (*execEvaluator)(nil)
+
+func (e *execEvaluator) restart() error {
+	e.mu.Lock()
+	old := e.current
+	e.mu.Unlock()
+	if old != nil {
+		old.replaceOnce.Do(func() { close(old.replaced) })
+		select {
+		case <-old.done:
+		default:
+			if err := killProcess(old.cmd.Process); err != nil {
+				internal.Debug("Failed to kill process %d: %v", old.cmd.Process.Pid, err)
+			} else {
+				<-old.done
+			}
+		}
+	}
+	return e.init()
+}
+
+// start spawns a new Pkl process, and starts exchanging messages with it.
+func (e *execEvaluator) start() (*pklProcess, error) {
+	cmd := e.getStartCommand()
+	cmd.Env = e.process.environ()
+	cmd.Dir = e.process.WorkingDir
+	stderr := &lineTail{max: stderrTailLines}
+	cmd.Stderr = io.MultiWriter(e.process.stderr(), stderr)
+	// bounds how long waiting for the process waits for its standard error to be copied.
+	cmd.WaitDelay = time.Second
+	stdin, err := cmd.StdinPipe()
 	if err != nil {
-		return err
+		return nil, err
 	}
-	go e.readIncomingMessages(stdout)
-	go e.handleSendMessages(stdin)
-	internal.Debug("Spawning command: %s", e.cmd)
-	err = e.cmd.Start()
+	stdout, err := cmd.StdoutPipe()
 	if err != nil {
-		return err
+		return nil, err
 	}
-	go e.listenForProcessClose()
-	return nil
+	proc := &pklProcess{
+		cmd:      cmd,
+		done:     make(chan struct{}),
+		replaced: make(chan struct{}),
+		stderr:   stderr,
+	}
+	internal.Debug("Spawning command: %s", cmd)
+	if err = cmd.Start(); err != nil {
+		return nil, err
+	}
+	proc.started = time.Now()
+	go e.readIncomingMessages(proc, stdout)
+	go e.handleSendMessages(proc, stdin)
+	go e.listenForProcessClose(proc)
+	return proc, nil
+}
+
+// processExited notifies the evaluator manager that proc has stopped working.
+//
+// Only the first call for a given process is reported, and nothing is reported if the
+// evaluator was closed explicitly, or if the process has since been replaced.
+func (e *execEvaluator) processExited(proc *pklProcess, err error) {
+	proc.exitOnce.Do(func() {
+		e.reportMu.RLock()
+		defer e.reportMu.RUnlock()
+		if e.exited.get() {
+			return
+		}
+		select {
+		case e.closed <- err:
+		case <-proc.replaced:
+		case <-e.closing:
+		}
+	})
 }
 
 // listenForProcessClose notifies the evaluator manager when the process quits.
-func (e *execEvaluator) listenForProcessClose() {
-	defer close(e.processDone)
-	err := e.cmd.Wait()
-	// e.exited gets set if closed explicitly.
-	if e.exited.get() {
-		return
-	}
-	e.closed <- err
+func (e *execEvaluator) listenForProcessClose(proc *pklProcess) {
+	err := proc.cmd.Wait()
+	close(proc.done)
+	e.processExited(proc, newProcessExitedError(proc, err))
 }
 
-func (e *execEvaluator) readIncomingMessages(stdout io.Reader) {
+func (e *execEvaluator) readIncomingMessages(proc *pklProcess, stdout io.Reader) {
 	dec := msgpack.NewDecoder(stdout)
 	for {
 		msg, err := msgapi.Decode(dec)
-		if e.exited.get() || err == io.EOF {
+		// once the process exits, waiting for it closes stdout; the exit is reported by
+		// listenForProcessClose.
+		if e.exited.get() || err == io.EOF || errors.Is(err, os.ErrClosed) {
 			break
 		}
 		if err != nil {
-			e.closed <- &InternalError{err: err}
+			e.processExited(proc, &InternalError{err: err})
 			return
 		}
 		internal.Debug("Received message: %#v", msg)
-		e.in <- msg
+		if !e.deliver(msg) {
+			return
+		}
 	}
 }
 
-func (e *execEvaluator) handleSendMessages(stdin io.WriteCloser) {
+// deliver passes msg on to the evaluator manager.
+//
+// It reports false if the evaluator is closed explicitly.
+func (e *execEvaluator) deliver(msg msgapi.IncomingMessage) bool {
+	e.reportMu.RLock()
+	defer e.reportMu.RUnlock()
+	if e.exited.get() {
+		return false
+	}
+	select {
+	case e.in <- msg:
+		return true
+	case <-e.closing:
+		return false
+	}
+}
+
+func (e *execEvaluator) handleSendMessages(proc *pklProcess, stdin io.WriteCloser) {
 	defer func() {
 		if err := stdin.Close(); err != nil {
 			internal.Debug("Failed to close stdin: %v", err)
 		}
 	}()
 
-	for msg := range e.out {
+	for {
+		var msg msgapi.OutgoingMessage
+		select {
+		case <-proc.done:
+			return
+		case m, ok := <-e.out:
+			if !ok {
+				return
+			}
+			msg = m
+		}
 		internal.Debug("Sending message: %#v", msg)
 		b, err := msg.ToMsgPack()
 		if err != nil {
-			e.closed <- &InternalError{err: err}
+			e.processExited(proc, &InternalError{err: err})
 			return
 		}
 		if _, err = stdin.Write(b); err != nil {
-			if !e.exited.get() {
-				e.closed <- &InternalError{err: err}
-			}
+			e.processExited(proc, &InternalError{err: err})
 			return
 		}
 	}
 }
 
 func (e *execEvaluator) deinit() error {
-	if e.cmd == nil {
+	e.mu.Lock()
+	proc := e.current
+	e.mu.Unlock()
+	if proc == nil {
 		return nil
 	}
 	e.exited.set(true)
+	close(e.closing)
+	// wait for messages and exits that are being reported before closing the channels.
+	e.reportMu.Lock()
 	close(e.in)
 	close(e.out)
 	close(e.closed)
+	e.reportMu.Unlock()
 
-	return e.enforceKillOnTimeout()
+	return e.enforceKillOnTimeout(proc)
 }
 
-func (e *execEvaluator) enforceKillOnTimeout() error {
+func (e *execEvaluator) enforceKillOnTimeout(proc *pklProcess) error {
+	gracePeriod := e.process.ShutdownGracePeriod
+	if gracePeriod == 0 {
+		gracePeriod = defaultShutdownGracePeriod
+	}
 	select {
-	case <-time.After(5 * time.Second):
-		if err := killProcess(e.cmd.Process); err != nil {
-			return fmt.Errorf("failed to kill process %d: %v", e.cmd.Process.Pid, err)
+	case <-time.After(gracePeriod):
+		if err := killProcess(proc.cmd.Process); err != nil {
+			return fmt.Errorf("failed to kill process %d: %v", proc.cmd.Process.Pid, err)
 		}
-	case <-e.processDone:
+	case <-proc.done:
 		// The process has finished
 	}
 	return nil
@@ -21,13 +21,17 @@ package pkl
 import (
 	"os"
 	"os/exec"
+	"slices"
 	"syscall"
 )
 
 func (e *execEvaluator) getStartCommand() *exec.Cmd {
 	exe, arg := e.getCommandAndArgStrings()
-	cmd := exec.Command(exe, append(arg, "server")...)
+	cmd := exec.Command(exe, slices.Concat(arg, e.process.ExtraArgs, []string{"server"})...)
 	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if e.process.KillOnParentDeath {
+		setParentDeathSignal(cmd.SysProcAttr)
+	}
 	return cmd
 }
 
@@ -19,12 +19,13 @@ package pkl
 import (
 	"os"
 	"os/exec"
+	"slices"
 	"strconv"
 )
 
 func (e *execEvaluator) getStartCommand() *exec.Cmd {
 	cmd, arg := e.getCommandAndArgStrings()
-	return exec.Command(cmd, append(arg, "server")...)
+	return exec.Command(cmd, slices.Concat(arg, e.process.ExtraArgs, []string{"server"})...)
 }
 
 func killProcess(proc *os.Process) error {
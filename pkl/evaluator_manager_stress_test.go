@@ -0,0 +1,95 @@
+//===----------------------------------------------------------------------===//
+// Copyright © 2026 Apple Inc. and the Pkl project authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//===----------------------------------------------------------------------===//
+
+package pkl_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/apple/pkl-go/pkl"
+	"github.com/apple/pkl-go/pkl/pkltest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvaluatorManager_stress(t *testing.T) {
+	server := pkltest.NewServer(pkltest.WithStrayMessages)
+	server.HandleEvaluate(func(call *pkltest.Call) ([]byte, error) {
+		return pkltest.Encode(nil)
+	})
+	manager := newServerManager(t, server)
+
+	const workers = 16
+	const iterations = 25
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*iterations*4)
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range iterations {
+				ev, err := manager.NewEvaluator(context.Background())
+				if err != nil {
+					errs <- err
+					continue
+				}
+				var evalWg sync.WaitGroup
+				for j := range 3 {
+					evalWg.Add(1)
+					go func() {
+						defer evalWg.Done()
+						ctx := context.Background()
+						if j == 2 {
+							var cancel context.CancelFunc
+							ctx, cancel = context.WithTimeout(ctx, time.Duration(i%3)*time.Millisecond)
+							defer cancel()
+						}
+						_, err := ev.EvaluateExpressionRaw(ctx, pkl.TextSource("foo = 1"), "foo")
+						var canceledErr *pkl.CanceledError
+						if err != nil && !errors.Is(err, pkl.ErrEvaluatorClosed) && !errors.As(err, &canceledErr) {
+							errs <- err
+						}
+					}()
+				}
+				if i%2 == 0 {
+					// close while evaluations may still be in flight.
+					_ = ev.Close()
+					evalWg.Wait()
+				} else {
+					evalWg.Wait()
+					_ = ev.Close()
+				}
+				_ = ev.Closed()
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+	assert.Eventually(t, func() bool { return len(server.Evaluators()) == 0 }, time.Second, 10*time.Millisecond)
+
+	// the dispatch loop survived every stray message.
+	ev, err := manager.NewEvaluator(context.Background())
+	if assert.NoError(t, err) {
+		_, err = ev.EvaluateExpressionRaw(context.Background(), pkl.TextSource("foo = 1"), "foo")
+		assert.NoError(t, err)
+	}
+}
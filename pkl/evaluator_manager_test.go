@@ -1,5 +1,5 @@
 //===----------------------------------------------------------------------===//
-// Copyright © 2024-2025 Apple Inc. and the Pkl project authors. All rights reserved.
+// Copyright © 2026 Apple Inc. and the Pkl project authors. All rights reserved.
 //
 // Licensed under the Apache License, Version 2.0 (the "License");
 // you may not use this file except in compliance with the License.
@@ -14,87 +14,263 @@
 // limitations under the License.
 //===----------------------------------------------------------------------===//
 
-package pkl
+package pkl_test
 
 import (
 	"context"
 	"errors"
-	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
-	"github.com/apple/pkl-go/pkl/internal"
-	"github.com/apple/pkl-go/pkl/internal/msgapi"
+	"github.com/apple/pkl-go/pkl"
+	"github.com/apple/pkl-go/pkl/pkltest"
 	"github.com/stretchr/testify/assert"
 )
 
-type fakeEvaluatorImpl struct {
-	in      chan msgapi.IncomingMessage
-	out     chan msgapi.OutgoingMessage
-	version string
-	closed  chan error
+// hangUntilClosed is an EvaluateHandler for evaluations that never complete.
+func hangUntilClosed(call *pkltest.Call) ([]byte, error) {
+	<-call.Done()
+	return nil, errors.New("evaluator closed")
 }
 
-func (f *fakeEvaluatorImpl) getVersion() (*internal.Semver, error) {
-	if f.version == "" {
-		return internal.PklVersion0_25, nil
+func TestEvaluator_canceled(t *testing.T) {
+	server := pkltest.NewServer()
+	server.HandleEvaluate(func(call *pkltest.Call) ([]byte, error) {
+		if call.Expr == "hang" {
+			return hangUntilClosed(call)
+		}
+		return pkltest.Encode(call.Expr)
+	})
+	closed := make(chan int64, 1)
+	server.HandleCloseEvaluator(func(evaluatorId int64) { closed <- evaluatorId })
+	manager := newServerManager(t, server)
+
+	ev, err := manager.NewEvaluator(context.Background())
+	if !assert.NoError(t, err) {
+		return
 	}
-	return internal.ParseSemver(f.version)
-}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	out, err := ev.EvaluateExpressionRaw(ctx, pkl.TextSource("foo = 1"), "hang")
+	assert.Nil(t, out)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	var canceledError *pkl.CanceledError
+	assert.ErrorAs(t, err, &canceledError)
 
-func (f *fakeEvaluatorImpl) init() error {
-	return nil
+	// the runaway evaluation is stopped by closing the evaluator, which is then replaced.
+	assert.Equal(t, int64(1), <-closed)
+	assert.Eventually(t, func() bool {
+		evaluators := server.Evaluators()
+		return len(evaluators) == 1 && evaluators[0].EvaluatorId == 2
+	}, time.Second, 10*time.Millisecond)
+	assert.False(t, ev.Closed())
+	out, err = ev.EvaluateExpressionRaw(context.Background(), pkl.TextSource("foo = 1"), "foo")
+	assert.NoError(t, err)
+	assert.Equal(t, pkltest.MustEncode("foo"), out)
 }
 
-func (f *fakeEvaluatorImpl) deinit() error {
-	return nil
-}
+func TestEvaluator_canceled_resetTimeout(t *testing.T) {
+	server := pkltest.NewServer()
+	server.HandleEvaluate(hangUntilClosed)
+	release := make(chan struct{})
+	defer close(release)
+	var created atomic.Bool
+	server.HandleCreateEvaluator(func(req pkltest.CreateEvaluatorRequest) error {
+		// only the first evaluator is created; its replacement never is.
+		if created.Swap(true) {
+			<-release
+			return errors.New("released")
+		}
+		return nil
+	})
+	manager := newServerManager(t, server)
 
-func (f *fakeEvaluatorImpl) inChan() chan msgapi.IncomingMessage {
-	return f.in
+	ev, err := manager.NewEvaluator(context.Background(), pkl.WithTimeout(50*time.Millisecond))
+	if !assert.NoError(t, err) {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = ev.EvaluateExpressionRaw(ctx, pkl.TextSource("foo = 1"), "")
+	assert.Error(t, err)
+	// the reset gives up after the evaluator's timeout, instead of blocking the evaluator forever.
+	assert.Eventually(t, ev.Closed, time.Second, 10*time.Millisecond)
+	assert.NoError(t, ev.Close())
 }
 
-func (f *fakeEvaluatorImpl) outChan() chan msgapi.OutgoingMessage {
-	return f.out
+func TestEvaluator_Timeout(t *testing.T) {
+	server := pkltest.NewServer()
+	server.HandleEvaluate(func(call *pkltest.Call) ([]byte, error) {
+		if call.Expr == "timeout" {
+			return nil, pkltest.Errorf("Evaluation timed out after 1 second(s).")
+		}
+		return hangUntilClosed(call)
+	})
+	closed := make(chan int64, 2)
+	server.HandleCloseEvaluator(func(evaluatorId int64) { closed <- evaluatorId })
+	manager := newServerManager(t, server)
+
+	ev, err := manager.NewEvaluator(context.Background(), pkl.WithTimeout(50*time.Millisecond))
+	if !assert.NoError(t, err) {
+		return
+	}
+	t.Run("timeout is shorter than the deadline", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		_, err := ev.EvaluateExpressionRaw(ctx, pkl.TextSource("foo = 1"), "")
+		var timeoutError *pkl.TimeoutError
+		if assert.ErrorAs(t, err, &timeoutError) {
+			assert.Equal(t, 50*time.Millisecond, timeoutError.Timeout)
+		}
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		<-closed
+	})
+	t.Run("deadline is shorter than the timeout", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		_, err := ev.EvaluateExpressionRaw(ctx, pkl.TextSource("foo = 1"), "")
+		var canceledError *pkl.CanceledError
+		assert.ErrorAs(t, err, &canceledError)
+		var timeoutError *pkl.TimeoutError
+		assert.NotErrorAs(t, err, &timeoutError)
+		<-closed
+	})
+	t.Run("timeout is enforced by Pkl", func(t *testing.T) {
+		_, err := ev.EvaluateExpressionRaw(context.Background(), pkl.TextSource("foo = 1"), "timeout")
+		var timeoutError *pkl.TimeoutError
+		assert.ErrorAs(t, err, &timeoutError)
+		var evalError *pkl.EvalError
+		if assert.ErrorAs(t, err, &evalError) {
+			assert.Equal(t, pkl.EvalErrorTimeout, evalError.Kind)
+		}
+	})
 }
 
-func (f *fakeEvaluatorImpl) closedChan() chan error {
-	return f.closed
+func TestEvaluatorManager_Shutdown(t *testing.T) {
+	server := pkltest.NewServer()
+	release := make(chan struct{})
+	server.HandleEvaluate(func(call *pkltest.Call) ([]byte, error) {
+		<-release
+		return pkltest.Encode(nil)
+	})
+	manager := newServerManager(t, server)
+
+	ev, err := manager.NewEvaluator(context.Background())
+	if !assert.NoError(t, err) {
+		return
+	}
+	result := make(chan error, 1)
+	go func() {
+		_, err := ev.EvaluateExpressionRaw(context.Background(), pkl.TextSource("foo = 1"), "foo")
+		result <- err
+	}()
+	assert.Eventually(t, func() bool { return server.Evaluations() == 1 }, time.Second, time.Millisecond)
+
+	shutdown := make(chan error, 1)
+	go func() {
+		shutdown <- manager.Shutdown(context.Background())
+	}()
+	assert.Eventually(t, func() bool {
+		_, err := manager.NewEvaluator(context.Background())
+		return errors.Is(err, pkl.ErrManagerClosed)
+	}, time.Second, time.Millisecond)
+	_, err = ev.EvaluateExpressionRaw(context.Background(), pkl.TextSource("foo = 1"), "foo")
+	assert.ErrorIs(t, err, pkl.ErrManagerClosed)
+	assert.Equal(t, 1, server.Evaluations())
+	assert.Empty(t, shutdown)
+
+	// the in-flight evaluation completes before the manager closes.
+	close(release)
+	assert.NoError(t, <-result)
+	assert.NoError(t, <-shutdown)
+	assert.True(t, ev.Closed())
 }
 
-var _ evaluatorManagerImpl = (*fakeEvaluatorImpl)(nil)
-
-func newFakeEvaluatorManager() *evaluatorManager {
-	return &evaluatorManager{
-		impl: &fakeEvaluatorImpl{
-			in:     make(chan msgapi.IncomingMessage),
-			out:    make(chan msgapi.OutgoingMessage),
-			closed: make(chan error),
-		},
-		interrupts:        &sync.Map{},
-		evaluators:        &sync.Map{},
-		pendingEvaluators: &sync.Map{},
+func TestEvaluatorManager_Shutdown_deadline(t *testing.T) {
+	server := pkltest.NewServer()
+	server.HandleEvaluate(hangUntilClosed)
+	manager := newServerManager(t, server)
+
+	ev, err := manager.NewEvaluator(context.Background())
+	if !assert.NoError(t, err) {
+		return
 	}
+	result := make(chan error, 1)
+	go func() {
+		_, err := ev.EvaluateExpressionRaw(context.Background(), pkl.TextSource("foo = 1"), "foo")
+		result <- err
+	}()
+	assert.Eventually(t, func() bool { return server.Evaluations() == 1 }, time.Second, time.Millisecond)
+
+	// the in-flight evaluation never completes, so it is interrupted once the deadline passes.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, manager.Shutdown(ctx), context.DeadlineExceeded)
+	assert.Error(t, <-result)
+	assert.True(t, ev.Closed())
 }
 
-func TestEvaluatorManager_interrupt_NewEvaluator(t *testing.T) {
-	m := newFakeEvaluatorManager()
-	defer assert.NoError(t, m.Close())
-	go m.listen()
+func TestEvaluatorManager_canceled_NewEvaluator(t *testing.T) {
+	server := pkltest.NewServer()
+	requested := make(chan struct{})
+	release := make(chan struct{})
+	server.HandleCreateEvaluator(func(req pkltest.CreateEvaluatorRequest) error {
+		close(requested)
+		<-release
+		return nil
+	})
+	closed := make(chan int64, 1)
+	server.HandleCloseEvaluator(func(evaluatorId int64) { closed <- evaluatorId })
+	manager := newServerManager(t, server)
+
+	ctx, cancel := context.WithCancel(context.Background())
 	go func() {
-		m.interrupt(errors.New("test interruption"))
+		<-requested
+		cancel()
 	}()
-	evaluator, err := m.NewEvaluator(context.Background())
-	assert.Nil(t, evaluator)
-	assert.Error(t, err, "test interruption")
+	ev, err := manager.NewEvaluator(ctx)
+	assert.Nil(t, ev)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// a late response creates an evaluator that nobody uses, so it gets closed.
+	close(release)
+	assert.Equal(t, int64(1), <-closed)
+	assert.Empty(t, server.Evaluators())
 }
 
-func TestEvaluatorManager_interrupt_Close(t *testing.T) {
-	m := newFakeEvaluatorManager()
-	go m.listen()
+func TestEvaluator_closed(t *testing.T) {
+	server := pkltest.NewServer()
+	server.HandleEvaluate(hangUntilClosed)
+	manager := newServerManager(t, server)
+
+	ev, err := manager.NewEvaluator(context.Background())
+	if !assert.NoError(t, err) {
+		return
+	}
+	errs := make(chan error)
 	go func() {
-		_ = m.Close()
+		_, err := ev.EvaluateExpressionRaw(context.Background(), pkl.TextSource("foo = 1"), "foo")
+		errs <- err
 	}()
-	evaluator, err := m.NewEvaluator(context.Background())
-	assert.Nil(t, evaluator)
-	assert.Nil(t, err)
+	assert.Eventually(t, func() bool { return server.Evaluations() == 1 }, time.Second, time.Millisecond)
+	assert.NoError(t, ev.Close())
+	assert.ErrorIs(t, <-errs, pkl.ErrEvaluatorClosed)
+	_, err = ev.EvaluateExpressionRaw(context.Background(), pkl.TextSource("foo = 1"), "foo")
+	assert.ErrorIs(t, err, pkl.ErrEvaluatorClosed)
+}
+
+func TestEvaluatorManager_NewEvaluator_optionErrors(t *testing.T) {
+	manager := newServerManager(t, pkltest.NewServer())
+
+	t.Setenv("HOME", "")
+	errOption := errors.New("option failed")
+	_, err := manager.NewEvaluator(
+		context.Background(),
+		pkl.PreconfiguredOptions,
+		pkl.WithFallibleOption(func(opts *pkl.EvaluatorOptions) error { return errOption }),
+		pkl.WithFallibleOption(func(opts *pkl.EvaluatorOptions) error { return nil }),
+	)
+	assert.ErrorIs(t, err, errOption)
+	assert.ErrorContains(t, err, "failed to determine the default cache directory")
 }
@@ -17,13 +17,16 @@
 package pkl
 
 import (
+	"errors"
 	"fmt"
 	"io/fs"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/apple/pkl-go/pkl/internal"
 	"github.com/apple/pkl-go/pkl/internal/msgapi"
@@ -119,26 +122,93 @@ type EvaluatorOptions struct {
 	// Settings for controlling how Pkl talks over HTTP(S).
 	//
 	// Added in Pkl 0.26.
-	// If the underlying Pkl does not support HTTP options, NewEvaluator will return with an error.
+	// If the underlying Pkl does not support HTTP options, NewEvaluator returns an *UnsupportedFeatureError.
 	Http *Http
 
 	// ExternalModuleReaders registers external commands that implement module reader schemes.
 	//
 	// Added in Pkl 0.27.
-	// If the underlying Pkl does not support external readers, evaluation will fail when a registered scheme is used.
+	// If the underlying Pkl does not support external readers, NewEvaluator returns an *UnsupportedFeatureError.
 	ExternalModuleReaders map[string]ExternalReader
 
 	// ExternalResourceReaders registers external commands that implement resource reader schemes.
 	//
 	// Added in Pkl 0.27.
-	// If the underlying Pkl does not support external readers, evaluation will fail when a registered scheme is used.
+	// If the underlying Pkl does not support external readers, NewEvaluator returns an *UnsupportedFeatureError.
 	ExternalResourceReaders map[string]ExternalReader
 
 	// TraceMode dictates how Pkl will format messages logged by `trace()`.
 	//
 	// Added in Pkl 0.30.
-	// If the underlying Pkl does not support trace modes, this option will be ignored.
+	// If the underlying Pkl does not support trace modes, NewEvaluator returns an *UnsupportedFeatureError.
 	TraceMode TraceMode
+
+	// Timeout is the maximum duration of a single evaluation.
+	//
+	// The timeout is enforced by Pkl, and also applied to the context of each evaluation, so that
+	// the shorter of Timeout and the context's deadline wins.
+	// An evaluation that exceeds Timeout fails with a *TimeoutError.
+	//
+	// Pkl measures timeouts in whole seconds, and rounds Timeout up accordingly.
+	Timeout time.Duration
+
+	// EvaluationCache, if set, caches the results of evaluations.
+	//
+	// If the evaluator has ModuleReaders or ResourceReaders, results are only cached if
+	// EvaluationCacheNamespace is also set.
+	EvaluationCache *EvaluationCache
+
+	// EvaluationCacheNamespace separates the results of evaluators that share an EvaluationCache.
+	//
+	// The cache cannot tell apart Go readers of the same type and scheme that read different
+	// data, so evaluators whose readers serve different data must use different namespaces.
+	// Evaluators with the same options and the same namespace share cached results.
+	EvaluationCacheNamespace string
+
+	// Interceptors wrap the evaluations, log messages, and reader calls of the evaluator.
+	Interceptors []Interceptor
+
+	// MetricsRecorders receive the metrics of the evaluator.
+	MetricsRecorders []MetricsRecorder
+
+	// RequiredPklVersion is a constraint that the version of Pkl must satisfy, for example
+	// ">=0.29, <0.33".
+	//
+	// If the underlying Pkl does not satisfy it, NewEvaluator returns an *UnsupportedFeatureError.
+	RequiredPklVersion string
+
+	// errs are the errors reported by options through AddError.
+	errs []error
+
+	// sandbox is set by WithSandbox.
+	sandbox *sandboxState
+
+	// cliDefaults is set by WithCliDefaults.
+	cliDefaults bool
+}
+
+// AddError reports that an option could not be applied.
+//
+// Instead of creating an evaluator, NewEvaluator returns the reported errors, joined into one
+// error. A nil err is ignored.
+func (e *EvaluatorOptions) AddError(err error) {
+	if err != nil {
+		e.errs = append(e.errs, err)
+	}
+}
+
+// Err returns the errors reported through AddError, joined into one error, or nil if there are none.
+func (e *EvaluatorOptions) Err() error {
+	return errors.Join(e.errs...)
+}
+
+// WithFallibleOption turns an option that can fail into an option accepted by NewEvaluator.
+//
+// If opt returns an error, it is reported through EvaluatorOptions.AddError.
+var WithFallibleOption = func(opt func(opts *EvaluatorOptions) error) func(opts *EvaluatorOptions) {
+	return func(opts *EvaluatorOptions) {
+		opts.AddError(opt(opts))
+	}
 }
 
 type TraceMode string
@@ -225,7 +295,7 @@ type Http struct {
 	// HTTP URI rewrite rules.
 	//
 	// Added in Pkl 0.29.
-	// If the underlying Pkl does not support HTTP rewrites, NewEvaluator will return an error.
+	// If the underlying Pkl does not support HTTP rewrites, NewEvaluator returns an *UnsupportedFeatureError.
 	//
 	// Each key-value pair designates a source prefix to a target prefix.
 	// Each rewrite rule must start with `http://` or `https://`, and end with `/`.
@@ -242,6 +312,8 @@ type Http struct {
 	// HTTP headers to add to outbound requests.
 	//
 	// Added in Pkl 0.32.
+	// If the underlying Pkl does not support HTTP headers, NewEvaluator returns an *UnsupportedFeatureError.
+	//
 	// Each key is a glob pattern, and each value is the collection of headers to add to matching requests.
 	//
 	// Before an HTTP request is made, each key is matched against the request URL.
@@ -324,6 +396,7 @@ type ExternalReader struct {
 	// The working directory to use for the executable process.
 	//
 	// Added in Pkl 0.32.
+	// If the underlying Pkl does not support it, NewEvaluator returns an *UnsupportedFeatureError.
 	WorkingDir string
 }
 
@@ -334,6 +407,7 @@ func (r *ExternalReader) toMessage() *msgapi.ExternalReader {
 	return &msgapi.ExternalReader{
 		Executable: r.Executable,
 		Arguments:  r.Arguments,
+		WorkingDir: r.WorkingDir,
 	}
 }
 
@@ -354,9 +428,17 @@ func (e *EvaluatorOptions) toMessage() *msgapi.CreateEvaluator {
 		ExternalModuleReaders:   externalReadersToMessage(e.ExternalModuleReaders),
 		ExternalResourceReaders: externalReadersToMessage(e.ExternalResourceReaders),
 		TraceMode:               string(e.TraceMode),
+		TimeoutSeconds:          timeoutSeconds(e.Timeout),
 	}
 }
 
+func timeoutSeconds(timeout time.Duration) int64 {
+	if timeout <= 0 {
+		return 0
+	}
+	return int64((timeout + time.Second - 1) / time.Second)
+}
+
 func (e *EvaluatorOptions) project() *msgapi.ProjectOrDependency {
 	if e.ProjectBaseURI == "" {
 		return nil
@@ -384,13 +466,19 @@ func buildEvaluatorOptions(version *internal.Semver, fns ...func(*EvaluatorOptio
 	for _, f := range fns {
 		f(o)
 	}
+	if err := o.Err(); err != nil {
+		return nil, err
+	}
+	if err := o.checkSandbox(); err != nil {
+		return nil, err
+	}
+	if o.sandbox != nil {
+		o.Interceptors = append(o.Interceptors, o.sandbox.limitOutput())
+	}
 	// repl:text is the URI of the module used to hold expressions. It should always be allowed.
 	o.AllowedModules = append(o.AllowedModules, "repl:text")
-	if o.Http != nil && internal.PklVersion0_26.IsGreaterThan(version) {
-		return nil, fmt.Errorf("http options are not supported on Pkl versions lower than 0.26")
-	}
-	if (len(o.ExternalModuleReaders) > 0 || len(o.ExternalResourceReaders) > 0) && internal.PklVersion0_27.IsGreaterThan(version) {
-		return nil, fmt.Errorf("external reader options are not supported on Pkl versions lower than 0.27")
+	if err := o.checkVersion(version); err != nil {
+		return nil, err
 	}
 	return o, nil
 }
@@ -406,15 +494,23 @@ var WithDefaultAllowedModules = func(opts *EvaluatorOptions) {
 }
 
 // WithDefaultCacheDir sets the cache directory to Pkl's default location.
-// It panics if the home directory cannot be determined.
+// It reports an error if the home directory cannot be determined.
 var WithDefaultCacheDir = func(opts *EvaluatorOptions) {
 	dirname, err := os.UserHomeDir()
 	if err != nil {
-		panic(err)
+		opts.AddError(fmt.Errorf("failed to determine the default cache directory: %w", err))
+		return
 	}
 	opts.CacheDir = filepath.Join(dirname, ".pkl/cache")
 }
 
+// WithTimeout sets the maximum duration of a single evaluation.
+var WithTimeout = func(timeout time.Duration) func(opts *EvaluatorOptions) {
+	return func(opts *EvaluatorOptions) {
+		opts.Timeout = timeout
+	}
+}
+
 // WithResourceReader sets up the given resource reader, and also adds the reader's scheme to the evaluator's
 // allowed resources list.
 var WithResourceReader = func(reader ResourceReader) func(opts *EvaluatorOptions) {
@@ -472,16 +568,29 @@ var WithProjectEvaluatorSettings = func(project *Project) func(opts *EvaluatorOp
 // WithProjectEvaluatorSettingsLegacy is like WithProjectEvaluatorSettings, but uses the _unresolved_ evaluator
 // settings.
 //
+// Like in the CLI, relative paths are resolved against the directory of a `file:` project.
+//
 // This should be avoided when using Pkl 0.32 or newer.
 var WithProjectEvaluatorSettingsLegacy = func(project *Project) func(opts *EvaluatorOptions) {
 	return func(opts *EvaluatorOptions) {
-		applyFromProjectEvaluatorSettings(project.EvaluatorSettings, opts)
+		settings := project.EvaluatorSettings
+		if projectFileUri, err := url.Parse(project.ProjectFileUri); err == nil && projectFileUri.Scheme == "file" {
+			resolveEvaluatorSettingsPaths(&settings, filepath.Dir(filepath.FromSlash(projectFileUri.Path)))
+		}
+		applyFromProjectEvaluatorSettings(settings, opts)
 	}
 }
 
+// applyFromProjectEvaluatorSettings applies the settings that are set in evaluatorSettings to opts.
+//
+// Like in the CLI, options whose setting is not set keep their current value.
 var applyFromProjectEvaluatorSettings = func(evaluatorSettings ProjectEvaluatorSettings, opts *EvaluatorOptions) {
-	opts.Properties = evaluatorSettings.ExternalProperties
-	opts.Env = evaluatorSettings.Env
+	if evaluatorSettings.ExternalProperties != nil {
+		opts.Properties = evaluatorSettings.ExternalProperties
+	}
+	if evaluatorSettings.Env != nil {
+		opts.Env = evaluatorSettings.Env
+	}
 	if evaluatorSettings.AllowedModules != nil {
 		opts.AllowedModules = *evaluatorSettings.AllowedModules
 	}
@@ -490,10 +599,18 @@ var applyFromProjectEvaluatorSettings = func(evaluatorSettings ProjectEvaluatorS
 	}
 	if evaluatorSettings.NoCache != nil && *evaluatorSettings.NoCache {
 		opts.CacheDir = ""
-	} else {
+	} else if evaluatorSettings.ModuleCacheDir != "" {
 		opts.CacheDir = evaluatorSettings.ModuleCacheDir
 	}
-	opts.RootDir = evaluatorSettings.RootDir
+	if evaluatorSettings.ModulePath != nil {
+		opts.ModulePaths = evaluatorSettings.ModulePath
+	}
+	if evaluatorSettings.RootDir != "" {
+		opts.RootDir = evaluatorSettings.RootDir
+	}
+	if evaluatorSettings.Timeout.Value != 0 {
+		opts.Timeout = evaluatorSettings.Timeout.GoDuration()
+	}
 	if evaluatorSettings.Http != nil {
 		opts.Http = &Http{}
 		if evaluatorSettings.Http.Proxy != nil {
@@ -522,11 +639,11 @@ var applyFromProjectEvaluatorSettings = func(evaluatorSettings ProjectEvaluatorS
 							if vv, ok := v.(string); ok {
 								h.Add(header, vv)
 							} else {
-								panic(fmt.Sprintf("unexpected value of type %T in project at evaluatorSettings.http.headers[%q][%q][%d]", value, pattern, header, idx))
+								opts.AddError(fmt.Errorf("unexpected value of type %T in project at evaluatorSettings.http.headers[%q][%q][%d]", v, pattern, header, idx))
 							}
 						}
 					default:
-						panic(fmt.Sprintf("unexpected value of type %T in project at evaluatorSettings.http.headers[%q][%q]", value, pattern, header))
+						opts.AddError(fmt.Errorf("unexpected value of type %T in project at evaluatorSettings.http.headers[%q][%q]", value, pattern, header))
 					}
 				}
 			}
@@ -534,20 +651,26 @@ var applyFromProjectEvaluatorSettings = func(evaluatorSettings ProjectEvaluatorS
 	}
 	if evaluatorSettings.ExternalModuleReaders != nil {
 		opts.ExternalModuleReaders = make(map[string]ExternalReader, len(evaluatorSettings.ExternalModuleReaders))
+		// if no explicit allowed modules are set in the project, allow declared external module readers
+		if evaluatorSettings.AllowedModules == nil && len(opts.AllowedModules) == 0 {
+			WithDefaultAllowedModules(opts)
+		}
 		for scheme, reader := range evaluatorSettings.ExternalModuleReaders {
 			opts.ExternalModuleReaders[scheme] = ExternalReader(reader)
-			if evaluatorSettings.AllowedModules == nil { // if no explicit allowed modules are set in the project, allow declared external module readers
-				WithDefaultAllowedModules(opts)
+			if evaluatorSettings.AllowedModules == nil {
 				opts.AllowedModules = append(opts.AllowedModules, regexp.QuoteMeta(scheme+":"))
 			}
 		}
 	}
 	if evaluatorSettings.ExternalResourceReaders != nil {
 		opts.ExternalResourceReaders = make(map[string]ExternalReader, len(evaluatorSettings.ExternalResourceReaders))
+		// if no explicit allowed resources are set in the project, allow declared external resource readers
+		if evaluatorSettings.AllowedResources == nil && len(opts.AllowedResources) == 0 {
+			WithDefaultAllowedResources(opts)
+		}
 		for scheme, reader := range evaluatorSettings.ExternalResourceReaders {
 			opts.ExternalResourceReaders[scheme] = ExternalReader(reader)
-			if evaluatorSettings.AllowedResources == nil { // if no explicit allowed resources are set in the project, allow declared external resource readers
-				WithDefaultAllowedResources(opts)
+			if evaluatorSettings.AllowedResources == nil {
 				opts.AllowedResources = append(opts.AllowedResources, regexp.QuoteMeta(scheme+":"))
 			}
 		}
@@ -606,6 +729,9 @@ var WithExternalResourceReader = func(scheme string, spec ExternalReader) func(o
 // WithHttpHeaders configures the evaluator to send additional HTTP headers with requests whose URL matches the specified pattern.
 var WithHttpHeaders = func(pattern string, headers http.Header) func(opts *EvaluatorOptions) {
 	return func(opts *EvaluatorOptions) {
+		if opts.Http == nil {
+			opts.Http = &Http{}
+		}
 		if opts.Http.Headers == nil {
 			opts.Http.Headers = make(map[string]http.Header)
 		}
@@ -628,7 +754,7 @@ var WithHttpHeaders = func(pattern string, headers http.Header) func(opts *Evalu
 //   - ~/.pkl/cache as the cache directory
 //   - no-op logging
 //
-// It panics if the home directory cannot be determined.
+// It reports an error if the home directory cannot be determined.
 //
 //goland:noinspection GoUnusedGlobalVariable
 var PreconfiguredOptions = func(opts *EvaluatorOptions) {
@@ -639,10 +765,22 @@ var PreconfiguredOptions = func(opts *EvaluatorOptions) {
 	opts.Logger = NoopLogger
 }
 
+// WithCliDefaults makes EvaluatorManager.NewProjectEvaluator start out with the defaults of the
+// Pkl CLI (PreconfiguredOptions), before applying the project's evaluator settings, so that
+// projects evaluate like with `pkl eval --project-dir`.
+//
+// Among others, this lets the evaluator read the environment variables of the current process,
+// and read files and HTTP resources.
+//
+// It has no effect on other ways to create an evaluator.
+var WithCliDefaults = func(opts *EvaluatorOptions) {
+	opts.cliDefaults = true
+}
+
 // MaybePreconfiguredOptions is like PreconfiguredOptions, except it only applies options
 // if they have not already been set.
 //
-// It panics if the home directory cannot be determined.
+// It reports an error if the home directory cannot be determined.
 var MaybePreconfiguredOptions = func(opts *EvaluatorOptions) {
 	if len(opts.AllowedResources) == 0 {
 		WithDefaultAllowedResources(opts)
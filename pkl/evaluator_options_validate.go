@@ -0,0 +1,275 @@
+//===----------------------------------------------------------------------===//
+// Copyright © 2026 Apple Inc. and the Pkl project authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//===----------------------------------------------------------------------===//
+
+package pkl
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"regexp/syntax"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Validate checks the options for mistakes that Pkl would otherwise report when creating the
+// evaluator, or not at all:
+//   - AllowedModules and AllowedResources must be valid regular expressions
+//   - the keys and values of Http.Rewrites must be http(s) URL prefixes that end with `/`
+//   - Http.Proxy.Address must be an `http://` URL with only a host and port, and the entries of
+//     Http.Proxy.NoProxy must be hosts, IP addresses or CIDR blocks, optionally with a port
+//   - every module and resource reader scheme must only be set once; a scheme that is set by both
+//     a Go reader and an external reader, or by two Go readers, is rejected even though earlier
+//     versions of pkl-go accepted it
+//   - RootDir must be an existing directory, and CacheDir must be a directory or not exist yet
+//   - the executable of every external reader must resolve
+//
+// All problems are returned, joined into one error.
+//
+// Validate resolves relative paths against the current working directory, and executables in the
+// PATH of the current process. NewEvaluator resolves them against the WorkingDir and Env of the
+// ProcessOptions instead, and skips the checks against the file system if Pkl does not run as a
+// child process of the EvaluatorManager, because the paths may then refer to another host.
+func (e *EvaluatorOptions) Validate() error {
+	return e.validate(&ProcessOptions{})
+}
+
+// validate checks the options, resolving paths the way the Pkl process configured by process
+// does. If process is nil, the checks against the file system are skipped.
+func (e *EvaluatorOptions) validate(process *ProcessOptions) error {
+	var errs []error
+	report := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+	for i, pattern := range e.AllowedModules {
+		if err := checkJavaPattern(pattern); err != nil {
+			report("AllowedModules[%d]: invalid pattern %q: %w", i, pattern, err)
+		}
+	}
+	for i, pattern := range e.AllowedResources {
+		if err := checkJavaPattern(pattern); err != nil {
+			report("AllowedResources[%d]: invalid pattern %q: %w", i, pattern, err)
+		}
+	}
+	if e.Http != nil {
+		for _, from := range slices.Sorted(maps.Keys(e.Http.Rewrites)) {
+			if err := checkRewritePrefix(from); err != nil {
+				report("Http.Rewrites: invalid key %q: %w", from, err)
+			}
+			to := e.Http.Rewrites[from]
+			if err := checkRewritePrefix(to); err != nil {
+				report("Http.Rewrites[%q]: invalid value %q: %w", from, to, err)
+			}
+		}
+		if proxy := e.Http.Proxy; proxy != nil {
+			if proxy.Address != "" {
+				if err := checkProxyAddress(proxy.Address); err != nil {
+					report("Http.Proxy.Address: invalid address %q: %w", proxy.Address, err)
+				}
+			}
+			for i, entry := range proxy.NoProxy {
+				if err := checkNoProxy(entry); err != nil {
+					report("Http.Proxy.NoProxy[%d]: invalid entry %q: %w", i, entry, err)
+				}
+			}
+		}
+	}
+	moduleSchemes := slices.Collect(maps.Keys(e.ExternalModuleReaders))
+	for _, reader := range e.ModuleReaders {
+		moduleSchemes = append(moduleSchemes, reader.Scheme())
+	}
+	for _, scheme := range duplicates(moduleSchemes) {
+		report("module reader scheme %q is set more than once", scheme)
+	}
+	resourceSchemes := slices.Collect(maps.Keys(e.ExternalResourceReaders))
+	for _, reader := range e.ResourceReaders {
+		resourceSchemes = append(resourceSchemes, reader.Scheme())
+	}
+	for _, scheme := range duplicates(resourceSchemes) {
+		report("resource reader scheme %q is set more than once", scheme)
+	}
+	if process == nil {
+		return errors.Join(errs...)
+	}
+	if e.RootDir != "" {
+		if dir, err := process.resolve(e.RootDir); err != nil {
+			report("RootDir: %w", err)
+		} else if info, err := os.Stat(dir); err != nil {
+			report("RootDir: %w", err)
+		} else if !info.IsDir() {
+			report("RootDir: %s is not a directory", dir)
+		}
+	}
+	if e.CacheDir != "" {
+		if dir, err := process.resolve(e.CacheDir); err != nil {
+			report("CacheDir: %w", err)
+		} else if err := checkCacheDir(dir); err != nil {
+			report("CacheDir: %w", err)
+		}
+	}
+	for _, scheme := range slices.Sorted(maps.Keys(e.ExternalModuleReaders)) {
+		if err := e.ExternalModuleReaders[scheme].checkExecutable(process); err != nil {
+			report("ExternalModuleReaders[%q].Executable: %w", scheme, err)
+		}
+	}
+	for _, scheme := range slices.Sorted(maps.Keys(e.ExternalResourceReaders)) {
+		if err := e.ExternalResourceReaders[scheme].checkExecutable(process); err != nil {
+			report("ExternalResourceReaders[%q].Executable: %w", scheme, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// checkJavaPattern checks that pattern is a valid Java regular expression, as far as Go can tell.
+//
+// Constructs that are valid in Java but not supported by Go, such as lookarounds,
+// backreferences and Java's character classes (for example `\p{javaLowerCase}` or `\p{InGreek}`),
+// are left to Pkl.
+func checkJavaPattern(pattern string) error {
+	if strings.Contains(pattern, "(?P<") {
+		return errors.New("named groups are written as `(?<name>...)` in Java")
+	}
+	_, err := syntax.Parse(pattern, syntax.Perl)
+	var syntaxErr *syntax.Error
+	if errors.As(err, &syntaxErr) {
+		switch syntaxErr.Code {
+		case syntax.ErrInvalidPerlOp, syntax.ErrInvalidEscape, syntax.ErrInvalidRepeatOp:
+			return nil
+		case syntax.ErrInvalidCharRange:
+			// Go reports unknown `\p{...}` classes as invalid ranges.
+			if strings.HasPrefix(syntaxErr.Expr, `\p{`) || strings.HasPrefix(syntaxErr.Expr, `\P{`) {
+				return nil
+			}
+		}
+	}
+	return err
+}
+
+// checkRewritePrefix checks that prefix is a valid prefix of an HTTP rewrite.
+func checkRewritePrefix(prefix string) error {
+	u, err := url.Parse(prefix)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return errors.New("must start with `http://` or `https://`")
+	}
+	if u.Host == "" {
+		return errors.New("must have a host")
+	}
+	if !strings.HasSuffix(prefix, "/") {
+		return errors.New("must end with `/`")
+	}
+	return nil
+}
+
+// checkProxyAddress checks that address is an HTTP proxy address.
+func checkProxyAddress(address string) error {
+	u, err := url.Parse(address)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" {
+		return errors.New("must start with `http://`")
+	}
+	if u.Host == "" || u.User != nil || (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" {
+		return errors.New("must only have a host and an optional port")
+	}
+	return checkPort(u.Port())
+}
+
+var hostnamePattern = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9-]*[A-Za-z0-9])?(\.[A-Za-z0-9]([A-Za-z0-9-]*[A-Za-z0-9])?)*$`)
+
+// checkNoProxy checks that entry is a host, an IP address or a CIDR block, optionally with a port,
+// or `*`.
+func checkNoProxy(entry string) error {
+	if entry == "*" {
+		return nil
+	}
+	if _, _, err := net.ParseCIDR(entry); err == nil {
+		return nil
+	}
+	host := entry
+	if h, port, err := net.SplitHostPort(entry); err == nil {
+		if err := checkPort(port); err != nil {
+			return err
+		}
+		host = h
+	}
+	if net.ParseIP(host) != nil || hostnamePattern.MatchString(host) {
+		return nil
+	}
+	return errors.New("must be a host, an IP address or a CIDR block, optionally followed by a port")
+}
+
+func checkPort(port string) error {
+	if port == "" {
+		return nil
+	}
+	if p, err := strconv.Atoi(port); err != nil || p < 1 || p > 65535 {
+		return fmt.Errorf("invalid port %q", port)
+	}
+	return nil
+}
+
+// checkCacheDir checks that dir is a directory, or can be created.
+func checkCacheDir(dir string) error {
+	for path := dir; ; path = filepath.Dir(path) {
+		info, err := os.Stat(path)
+		if err == nil {
+			if !info.IsDir() {
+				return fmt.Errorf("%s is not a directory", path)
+			}
+			return nil
+		}
+		if !errors.Is(err, os.ErrNotExist) || filepath.Dir(path) == path {
+			return err
+		}
+	}
+}
+
+// checkExecutable checks that the executable of the reader resolves, as spawned by the Pkl process
+// configured by process.
+func (r ExternalReader) checkExecutable(process *ProcessOptions) error {
+	executable := r.Executable
+	if r.WorkingDir != "" && !filepath.IsAbs(executable) && strings.ContainsRune(executable, filepath.Separator) {
+		executable = filepath.Join(r.WorkingDir, executable)
+	}
+	_, err := process.lookPath(executable)
+	return err
+}
+
+// duplicates returns the elements that occur more than once in elements, in sorted order.
+func duplicates(elements []string) []string {
+	seen := make(map[string]int, len(elements))
+	for _, element := range elements {
+		seen[element]++
+	}
+	var result []string
+	for element, count := range seen {
+		if count > 1 {
+			result = append(result, element)
+		}
+	}
+	slices.Sort(result)
+	return result
+}
@@ -0,0 +1,153 @@
+//===----------------------------------------------------------------------===//
+// Copyright © 2026 Apple Inc. and the Pkl project authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//===----------------------------------------------------------------------===//
+
+package pkl_test
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"testing/fstest"
+
+	"github.com/apple/pkl-go/pkl"
+	"github.com/apple/pkl-go/pkl/pkltest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvaluatorOptions_Validate(t *testing.T) {
+	tempDir := t.TempDir()
+	file := filepath.Join(tempDir, "file.txt")
+	if err := os.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	valid := &pkl.EvaluatorOptions{
+		AllowedModules:   []string{"pkl:", "file:", `https://example\.com/`, "(?<name>foo):", "foo(?=bar)"},
+		AllowedResources: []string{"env:", `prop:\d+`, `prop:\p{javaLowerCase}+`, `prop:[\p{IsAlphabetic}\P{InGreek}]`},
+		RootDir:          tempDir,
+		CacheDir:         filepath.Join(tempDir, "cache", "pkl"),
+		Http: &pkl.Http{
+			Rewrites: map[string]string{"https://example.com/": "http://mirror.example.com:8080/example/"},
+			Proxy: &pkl.Proxy{
+				Address: "http://proxy.example.com:3128",
+				NoProxy: []string{"localhost", "127.0.0.1", "192.168.0.0/16", "example.com:443", "[::1]:8080", "::1"},
+			},
+		},
+		ExternalModuleReaders: map[string]pkl.ExternalReader{"bar": {Executable: executable}},
+		ExternalResourceReaders: map[string]pkl.ExternalReader{
+			// relative executables with a path separator resolve against the working directory.
+			"baz": {Executable: "." + string(filepath.Separator) + filepath.Base(executable), WorkingDir: filepath.Dir(executable)},
+		},
+	}
+	pkl.WithFs(fstest.MapFS{}, "foo")(valid)
+	t.Run("valid", func(t *testing.T) {
+		assert.NoError(t, valid.Validate())
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		invalid := &pkl.EvaluatorOptions{
+			AllowedModules:   []string{"pkl:", "file:(", "[z-a]"},
+			AllowedResources: []string{"(?P<name>env):"},
+			RootDir:          filepath.Join(tempDir, "missing"),
+			CacheDir:         file,
+			Http: &pkl.Http{
+				Rewrites: map[string]string{"https://example.com": "ftp://example.com/"},
+				Proxy: &pkl.Proxy{
+					Address: "https://proxy.example.com/path",
+					NoProxy: []string{"example.com:99999", "not a host"},
+				},
+			},
+			ExternalModuleReaders:   map[string]pkl.ExternalReader{"foo": {Executable: "pkl-go-missing-reader"}},
+			ExternalResourceReaders: map[string]pkl.ExternalReader{"baz": {Executable: filepath.Join(tempDir, "missing-reader")}},
+		}
+		pkl.WithFs(fstest.MapFS{}, "foo")(invalid)
+		pkl.WithFs(fstest.MapFS{}, "bar")(invalid)
+		pkl.WithFs(fstest.MapFS{}, "bar")(invalid)
+		err := invalid.Validate()
+		assert.ErrorContains(t, err, "AllowedModules[1]: invalid pattern \"file:(\": error parsing regexp: missing closing )")
+		assert.ErrorContains(t, err, "AllowedModules[2]: invalid pattern \"[z-a]\": error parsing regexp: invalid character class range")
+		assert.ErrorContains(t, err, "AllowedResources[0]: invalid pattern \"(?P<name>env):\": named groups are written as `(?<name>...)` in Java")
+		assert.ErrorContains(t, err, "Http.Rewrites: invalid key \"https://example.com\": must end with `/`")
+		assert.ErrorContains(t, err, "Http.Rewrites[\"https://example.com\"]: invalid value \"ftp://example.com/\": must start with `http://` or `https://`")
+		assert.ErrorContains(t, err, "Http.Proxy.Address: invalid address \"https://proxy.example.com/path\": must start with `http://`")
+		assert.ErrorContains(t, err, "Http.Proxy.NoProxy[0]: invalid entry \"example.com:99999\": invalid port \"99999\"")
+		assert.ErrorContains(t, err, "Http.Proxy.NoProxy[1]: invalid entry \"not a host\"")
+		assert.ErrorContains(t, err, "module reader scheme \"foo\" is set more than once")
+		assert.ErrorContains(t, err, "resource reader scheme \"bar\" is set more than once")
+		assert.ErrorContains(t, err, "RootDir: stat "+invalid.RootDir)
+		assert.ErrorContains(t, err, "CacheDir: "+file+" is not a directory")
+		assert.ErrorContains(t, err, "ExternalModuleReaders[\"foo\"].Executable: exec: \"pkl-go-missing-reader\"")
+		assert.ErrorContains(t, err, "ExternalResourceReaders[\"baz\"].Executable: exec: ")
+	})
+}
+
+func TestEvaluatorManager_NewEvaluator_validates_process(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("executables need an extension on Windows")
+	}
+	workingDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(workingDir, "root"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(workingDir, "bin"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(workingDir, "bin", "pkl-go-test-reader"), []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	options := func(opts *pkl.EvaluatorOptions) {
+		opts.RootDir = "root"
+		opts.CacheDir = filepath.Join("cache", "pkl")
+		opts.ExternalModuleReaders = map[string]pkl.ExternalReader{"foo": {Executable: "pkl-go-test-reader"}}
+		opts.ExternalResourceReaders = map[string]pkl.ExternalReader{"bar": {Executable: "./pkl-go-test-reader", WorkingDir: "bin"}}
+	}
+	newEvaluator := func(process pkl.ProcessOptions) error {
+		process.Stderr = io.Discard
+		manager := newFakePklManager(pkl.WithProcessOptions(process))
+		defer func() { assert.NoError(t, manager.Close()) }()
+		_, err := manager.NewEvaluator(context.Background(), options)
+		return err
+	}
+	// relative paths resolve against the working directory of the Pkl process, and executables
+	// in its PATH.
+	assert.NoError(t, newEvaluator(pkl.ProcessOptions{WorkingDir: workingDir, Env: map[string]string{"PATH": "bin"}}))
+
+	err := newEvaluator(pkl.ProcessOptions{WorkingDir: workingDir, ReplaceEnv: true})
+	assert.EqualError(t, err, "ExternalModuleReaders[\"foo\"].Executable: exec: \"pkl-go-test-reader\": executable file not found in $PATH")
+
+	err = newEvaluator(pkl.ProcessOptions{})
+	assert.ErrorContains(t, err, "RootDir: stat ")
+	assert.ErrorContains(t, err, "ExternalModuleReaders[\"foo\"].Executable: ")
+	assert.ErrorContains(t, err, "ExternalResourceReaders[\"bar\"].Executable: ")
+}
+
+func TestEvaluatorManager_NewEvaluator_validates(t *testing.T) {
+	manager := newServerManager(t, pkltest.NewServer())
+
+	_, err := manager.NewEvaluator(context.Background(), func(opts *pkl.EvaluatorOptions) {
+		opts.AllowedModules = []string{"file:("}
+		// Pkl does not run as a child process, so the paths are not checked.
+		opts.RootDir = "/nonexistent"
+	})
+	assert.EqualError(t, err, "AllowedModules[0]: invalid pattern \"file:(\": error parsing regexp: missing closing ): `file:(`")
+}
@@ -0,0 +1,369 @@
+//===----------------------------------------------------------------------===//
+// Copyright © 2026 Apple Inc. and the Pkl project authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//===----------------------------------------------------------------------===//
+
+package pkl
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+)
+
+// EvaluatorPool is an Evaluator that spreads evaluations across a fixed number of evaluators
+// created with the same options.
+//
+// Evaluators are created lazily, and are retired and replaced after serving
+// EvaluatorPoolOptions.MaxEvaluations evaluations, or after being idle for longer than
+// EvaluatorPoolOptions.MaxIdleTime.
+//
+// Closing the pool closes all of its evaluators, but not the EvaluatorManager that created them.
+type EvaluatorPool interface {
+	Evaluator
+}
+
+// EvaluatorPoolOptions is the set of options available to control an EvaluatorPool.
+type EvaluatorPoolOptions struct {
+	// Size is the number of evaluators kept by the pool.
+	//
+	// If zero, the pool keeps one evaluator.
+	Size int
+
+	// MaxConcurrency is the maximum number of evaluations that run at once across the pool.
+	// Calls beyond this limit block until a slot frees up, or until their context is done.
+	//
+	// If zero, defaults to Size.
+	MaxConcurrency int
+
+	// MaxEvaluations is the number of evaluations an evaluator serves before it is retired and
+	// replaced.
+	//
+	// If zero, evaluators are never retired based on usage.
+	MaxEvaluations int
+
+	// MaxIdleTime is how long an evaluator may go unused before it is retired.
+	//
+	// If zero, evaluators are never retired based on idleness.
+	MaxIdleTime time.Duration
+}
+
+// NewEvaluatorPool creates an EvaluatorPool whose evaluators are created by manager with the
+// provided options.
+func NewEvaluatorPool(manager EvaluatorManager, poolOptions EvaluatorPoolOptions, opts ...func(options *EvaluatorOptions)) (EvaluatorPool, error) {
+	if poolOptions.Size < 0 || poolOptions.MaxConcurrency < 0 || poolOptions.MaxEvaluations < 0 || poolOptions.MaxIdleTime < 0 {
+		return nil, errors.New("evaluator pool options must not be negative")
+	}
+	if poolOptions.Size == 0 {
+		poolOptions.Size = 1
+	}
+	if poolOptions.MaxConcurrency == 0 {
+		poolOptions.MaxConcurrency = poolOptions.Size
+	}
+	p := &evaluatorPool{
+		manager: manager,
+		options: poolOptions,
+		slots:   make([]*pooledEvaluator, poolOptions.Size),
+		sem:     make(chan struct{}, poolOptions.MaxConcurrency),
+		done:    make(chan struct{}),
+	}
+	p.evaluatorOptions = append(slices.Clone(opts), WithMetricsRecorder(&p.stats))
+	if poolOptions.MaxIdleTime > 0 {
+		go p.retireIdleEvaluators()
+	}
+	return p, nil
+}
+
+type evaluatorPool struct {
+	manager          EvaluatorManager
+	options          EvaluatorPoolOptions
+	evaluatorOptions []func(options *EvaluatorOptions)
+	sem              chan struct{}
+	stats            statsCollector
+	done             chan struct{}
+
+	// mu guards slots and closed.
+	mu sync.Mutex
+	// slots holds the evaluators of the pool. A nil slot gets filled on demand.
+	slots  []*pooledEvaluator
+	closed bool
+}
+
+type pooledEvaluator struct {
+	// Evaluator is nil while the evaluator is being created.
+	Evaluator
+	// created is closed once creating the evaluator has finished, successfully or not.
+	created     chan struct{}
+	evaluations int
+	inUse       int
+	lastUsed    time.Time
+	retired     bool
+}
+
+var _ EvaluatorPool = (*evaluatorPool)(nil)
+
+func (p *evaluatorPool) EvaluateModule(ctx context.Context, source *ModuleSource, out any) error {
+	return p.with(ctx, func(ev Evaluator) error {
+		return ev.EvaluateModule(ctx, source, out)
+	})
+}
+
+func (p *evaluatorPool) EvaluateOutputText(ctx context.Context, source *ModuleSource) (out string, err error) {
+	err = p.with(ctx, func(ev Evaluator) error {
+		out, err = ev.EvaluateOutputText(ctx, source)
+		return err
+	})
+	return out, err
+}
+
+func (p *evaluatorPool) EvaluateOutputBytes(ctx context.Context, source *ModuleSource) (out []byte, err error) {
+	err = p.with(ctx, func(ev Evaluator) error {
+		out, err = ev.EvaluateOutputBytes(ctx, source)
+		return err
+	})
+	return out, err
+}
+
+func (p *evaluatorPool) EvaluateOutputValue(ctx context.Context, source *ModuleSource, out any) error {
+	return p.with(ctx, func(ev Evaluator) error {
+		return ev.EvaluateOutputValue(ctx, source, out)
+	})
+}
+
+func (p *evaluatorPool) EvaluateOutputFiles(ctx context.Context, source *ModuleSource) (out map[string]string, err error) {
+	err = p.with(ctx, func(ev Evaluator) error {
+		out, err = ev.EvaluateOutputFiles(ctx, source)
+		return err
+	})
+	return out, err
+}
+
+func (p *evaluatorPool) EvaluateOutputFilesBytes(ctx context.Context, source *ModuleSource) (out map[string][]byte, err error) {
+	err = p.with(ctx, func(ev Evaluator) error {
+		out, err = ev.EvaluateOutputFilesBytes(ctx, source)
+		return err
+	})
+	return out, err
+}
+
+func (p *evaluatorPool) EvaluateExpression(ctx context.Context, source *ModuleSource, expr string, out any) error {
+	return p.with(ctx, func(ev Evaluator) error {
+		return ev.EvaluateExpression(ctx, source, expr, out)
+	})
+}
+
+func (p *evaluatorPool) EvaluateExpressionRaw(ctx context.Context, source *ModuleSource, expr string) (out []byte, err error) {
+	err = p.with(ctx, func(ev Evaluator) error {
+		out, err = ev.EvaluateExpressionRaw(ctx, source, expr)
+		return err
+	})
+	return out, err
+}
+
+// Stats returns the statistics of all evaluators of the pool, including retired ones.
+func (p *evaluatorPool) Stats() Stats {
+	return p.stats.snapshot()
+}
+
+func (p *evaluatorPool) Close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	close(p.done)
+	var toClose []*pooledEvaluator
+	for i, pe := range p.slots {
+		// evaluators that are still being created are closed by their creator.
+		if pe != nil && pe.Evaluator != nil {
+			toClose = append(toClose, pe)
+		}
+		p.slots[i] = nil
+	}
+	p.mu.Unlock()
+	var err error
+	for _, pe := range toClose {
+		// if an error occurs, still try to keep closing.
+		if cerr := pe.Close(); cerr != nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+func (p *evaluatorPool) Closed() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.closed
+}
+
+// with runs fn with an evaluator from the pool, waiting for a free slot if MaxConcurrency
+// evaluations are already running.
+func (p *evaluatorPool) with(ctx context.Context, fn func(ev Evaluator) error) error {
+	select {
+	case p.sem <- empty:
+	case <-ctx.Done():
+		return &CanceledError{Err: ctx.Err()}
+	}
+	defer func() { <-p.sem }()
+	pe, err := p.checkout(ctx)
+	if err != nil {
+		return err
+	}
+	defer p.checkin(pe)
+	return fn(pe)
+}
+
+// checkout picks the least busy evaluator, filling an empty slot instead if every evaluator is
+// busy.
+func (p *evaluatorPool) checkout(ctx context.Context) (*pooledEvaluator, error) {
+	for {
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			return nil, ErrEvaluatorClosed
+		}
+		emptySlot, best := -1, -1
+		var creating *pooledEvaluator
+		for i, pe := range p.slots {
+			if pe != nil && pe.Evaluator == nil {
+				creating = pe
+				continue
+			}
+			if pe != nil && pe.Closed() {
+				// for example, the evaluator failed to reset after a canceled evaluation.
+				p.retire(i)
+				pe = nil
+			}
+			switch {
+			case pe == nil:
+				if emptySlot == -1 {
+					emptySlot = i
+				}
+			case best == -1 || pe.inUse < p.slots[best].inUse:
+				best = i
+			}
+		}
+		if best != -1 && (p.slots[best].inUse == 0 || emptySlot == -1) {
+			pe := p.slots[best]
+			pe.inUse++
+			p.mu.Unlock()
+			return pe, nil
+		}
+		if emptySlot != -1 {
+			// reserve the slot, so that other callers do not race to fill it while the evaluator is
+			// created without holding the lock.
+			pe := &pooledEvaluator{created: make(chan struct{})}
+			p.slots[emptySlot] = pe
+			p.mu.Unlock()
+			return p.create(ctx, emptySlot, pe)
+		}
+		// every slot is being filled; wait for one of them.
+		p.mu.Unlock()
+		select {
+		case <-creating.created:
+		case <-ctx.Done():
+			return nil, &CanceledError{Err: ctx.Err()}
+		}
+	}
+}
+
+// create creates the evaluator of the slot at index i, which has been reserved for pe.
+//
+// If creating the evaluator fails, the slot is given back.
+func (p *evaluatorPool) create(ctx context.Context, i int, pe *pooledEvaluator) (*pooledEvaluator, error) {
+	ev, err := p.manager.NewEvaluator(ctx, p.evaluatorOptions...)
+	if err == nil && ev == nil {
+		err = ErrManagerClosed
+	}
+	p.mu.Lock()
+	defer close(pe.created)
+	if err != nil {
+		if p.slots[i] == pe {
+			p.slots[i] = nil
+		}
+		p.mu.Unlock()
+		return nil, err
+	}
+	if p.slots[i] != pe {
+		// the pool was closed while the evaluator was created.
+		p.mu.Unlock()
+		_ = ev.Close()
+		return nil, ErrEvaluatorClosed
+	}
+	pe.Evaluator = ev
+	pe.inUse++
+	p.mu.Unlock()
+	return pe, nil
+}
+
+func (p *evaluatorPool) checkin(pe *pooledEvaluator) {
+	p.mu.Lock()
+	pe.inUse--
+	pe.evaluations++
+	pe.lastUsed = time.Now()
+	if !pe.retired && p.options.MaxEvaluations > 0 && pe.evaluations >= p.options.MaxEvaluations {
+		for i, other := range p.slots {
+			if other == pe {
+				p.retire(i)
+			}
+		}
+	}
+	shouldClose := pe.retired && pe.inUse == 0
+	p.mu.Unlock()
+	if shouldClose {
+		_ = pe.Close()
+	}
+}
+
+// retire empties the slot at index i. The evaluator that was in the slot is closed once it is no
+// longer in use.
+//
+// Must be called while holding p.mu.
+func (p *evaluatorPool) retire(i int) {
+	p.slots[i].retired = true
+	p.slots[i] = nil
+}
+
+// minIdleCheckInterval is the shortest interval at which idle evaluators are looked for.
+const minIdleCheckInterval = 10 * time.Millisecond
+
+// retireIdleEvaluators periodically closes evaluators that have been idle for longer than
+// MaxIdleTime.
+func (p *evaluatorPool) retireIdleEvaluators() {
+	ticker := time.NewTicker(max(p.options.MaxIdleTime/2, minIdleCheckInterval))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+		}
+		var toClose []*pooledEvaluator
+		p.mu.Lock()
+		for i, pe := range p.slots {
+			if pe != nil && pe.Evaluator != nil && pe.inUse == 0 && time.Since(pe.lastUsed) > p.options.MaxIdleTime {
+				p.retire(i)
+				toClose = append(toClose, pe)
+			}
+		}
+		p.mu.Unlock()
+		for _, pe := range toClose {
+			_ = pe.Close()
+		}
+	}
+}
@@ -0,0 +1,207 @@
+//===----------------------------------------------------------------------===//
+// Copyright © 2026 Apple Inc. and the Pkl project authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//===----------------------------------------------------------------------===//
+
+package pkl_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/apple/pkl-go/pkl"
+	"github.com/apple/pkl-go/pkl/pkltest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvaluatorPool_MaxEvaluations(t *testing.T) {
+	server := pkltest.NewServer()
+	evaluations := make(chan int64, 16)
+	server.HandleEvaluate(func(call *pkltest.Call) ([]byte, error) {
+		evaluations <- call.EvaluatorId
+		return pkltest.Encode(nil)
+	})
+	closed := make(chan int64, 16)
+	server.HandleCloseEvaluator(func(evaluatorId int64) { closed <- evaluatorId })
+	manager := newServerManager(t, server)
+
+	pool, err := pkl.NewEvaluatorPool(manager, pkl.EvaluatorPoolOptions{Size: 1, MaxEvaluations: 2})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer func() { assert.NoError(t, pool.Close()) }()
+	for range 3 {
+		_, err := pool.EvaluateExpressionRaw(context.Background(), pkl.TextSource("foo = 1"), "foo")
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, int64(1), <-evaluations)
+	assert.Equal(t, int64(1), <-evaluations)
+	assert.Equal(t, int64(1), <-closed)
+	assert.Equal(t, int64(2), <-evaluations)
+}
+
+func TestEvaluatorPool_MaxIdleTime(t *testing.T) {
+	server := pkltest.NewServer()
+	server.HandleEvaluate(func(call *pkltest.Call) ([]byte, error) {
+		return pkltest.Encode(nil)
+	})
+	closed := make(chan int64, 16)
+	server.HandleCloseEvaluator(func(evaluatorId int64) { closed <- evaluatorId })
+	manager := newServerManager(t, server)
+
+	pool, err := pkl.NewEvaluatorPool(manager, pkl.EvaluatorPoolOptions{MaxIdleTime: 20 * time.Millisecond})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer func() { assert.NoError(t, pool.Close()) }()
+	_, err = pool.EvaluateExpressionRaw(context.Background(), pkl.TextSource("foo = 1"), "foo")
+	assert.NoError(t, err)
+	select {
+	case evaluatorId := <-closed:
+		assert.Equal(t, int64(1), evaluatorId)
+	case <-time.After(time.Second):
+		t.Fatal("expected idle evaluator to be closed")
+	}
+}
+
+func TestEvaluatorPool_MaxConcurrency(t *testing.T) {
+	server := pkltest.NewServer()
+	evaluations := make(chan chan struct{}, 16)
+	server.HandleEvaluate(func(call *pkltest.Call) ([]byte, error) {
+		release := make(chan struct{})
+		evaluations <- release
+		<-release
+		return pkltest.Encode(nil)
+	})
+	manager := newServerManager(t, server)
+
+	pool, err := pkl.NewEvaluatorPool(manager, pkl.EvaluatorPoolOptions{Size: 2, MaxConcurrency: 1})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer func() { assert.NoError(t, pool.Close()) }()
+	var wg sync.WaitGroup
+	for range 2 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := pool.EvaluateExpressionRaw(context.Background(), pkl.TextSource("foo = 1"), "foo")
+			assert.NoError(t, err)
+		}()
+	}
+	first := <-evaluations
+	select {
+	case <-evaluations:
+		t.Fatal("expected only one evaluation in flight")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(first)
+	close(<-evaluations)
+	wg.Wait()
+}
+
+// creatingManager is an EvaluatorManager whose NewEvaluator first calls create.
+type creatingManager struct {
+	pkl.EvaluatorManager
+	create func() error
+}
+
+func (m *creatingManager) NewEvaluator(ctx context.Context, opts ...func(options *pkl.EvaluatorOptions)) (pkl.Evaluator, error) {
+	if err := m.create(); err != nil {
+		return nil, err
+	}
+	return m.EvaluatorManager.NewEvaluator(ctx, opts...)
+}
+
+func TestEvaluatorPool_slowCreation(t *testing.T) {
+	server := pkltest.NewServer()
+	inFlight := make(chan int64, 16)
+	hang := make(chan struct{})
+	server.HandleEvaluate(func(call *pkltest.Call) ([]byte, error) {
+		if call.Expr == "hang" {
+			inFlight <- call.EvaluatorId
+			<-hang
+		}
+		return pkltest.Encode(nil)
+	})
+
+	release := make(chan struct{})
+	var calls sync.Mutex
+	var count int
+	manager := &creatingManager{EvaluatorManager: newServerManager(t, server), create: func() error {
+		calls.Lock()
+		count++
+		first := count == 1
+		calls.Unlock()
+		if first {
+			<-release
+			return errors.New("failed to create evaluator")
+		}
+		return nil
+	}}
+	pool, err := pkl.NewEvaluatorPool(manager, pkl.EvaluatorPoolOptions{Size: 2})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer func() { assert.NoError(t, pool.Close()) }()
+
+	failed := make(chan error)
+	go func() {
+		_, err := pool.EvaluateExpressionRaw(context.Background(), pkl.TextSource("foo = 1"), "foo")
+		failed <- err
+	}()
+	assert.Eventually(t, func() bool {
+		calls.Lock()
+		defer calls.Unlock()
+		return count == 1
+	}, time.Second, time.Millisecond)
+	// the pool is not locked while the first evaluator is created.
+	_, err = pool.EvaluateExpressionRaw(context.Background(), pkl.TextSource("foo = 1"), "foo")
+	assert.NoError(t, err)
+
+	close(release)
+	assert.EqualError(t, <-failed, "failed to create evaluator")
+	// the slot was given back, so both slots can be filled.
+	var wg sync.WaitGroup
+	for range 2 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := pool.EvaluateExpressionRaw(context.Background(), pkl.TextSource("foo = 1"), "hang")
+			assert.NoError(t, err)
+		}()
+	}
+	assert.NotEqual(t, <-inFlight, <-inFlight)
+	close(hang)
+	wg.Wait()
+}
+
+func TestEvaluatorPool_shortMaxIdleTime(t *testing.T) {
+	server := pkltest.NewServer()
+	server.HandleEvaluate(func(call *pkltest.Call) ([]byte, error) {
+		return pkltest.Encode(nil)
+	})
+	manager := newServerManager(t, server)
+
+	pool, err := pkl.NewEvaluatorPool(manager, pkl.EvaluatorPoolOptions{MaxIdleTime: time.Nanosecond})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer func() { assert.NoError(t, pool.Close()) }()
+	_, err = pool.EvaluateExpressionRaw(context.Background(), pkl.TextSource("foo = 1"), "foo")
+	assert.NoError(t, err)
+}
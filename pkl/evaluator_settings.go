@@ -0,0 +1,292 @@
+//===----------------------------------------------------------------------===//
+// Copyright © 2026 Apple Inc. and the Pkl project authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//===----------------------------------------------------------------------===//
+
+package pkl
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LoadEvaluatorOptions loads EvaluatorOptions from a module that amends `pkl:EvaluatorSettings`.
+//
+// The settings are mapped like the `evaluatorSettings` of a PklProject. If source is a file,
+// relative paths within the settings are resolved against the directory of the file.
+//
+// The returned options can be applied to a new evaluator with WithEvaluatorOptions.
+func LoadEvaluatorOptions(ctx context.Context, source *ModuleSource) (*EvaluatorOptions, error) {
+	ev, err := NewEvaluator(ctx, PreconfiguredOptions)
+	if err != nil {
+		return nil, err
+	}
+	opts, err := LoadEvaluatorOptionsFromEvaluator(ctx, ev, source)
+	if cerr := ev.Close(); err == nil && cerr != nil {
+		return nil, cerr
+	}
+	return opts, err
+}
+
+// LoadEvaluatorOptionsFromEvaluator is like LoadEvaluatorOptions, but uses the already provisioned
+// evaluator.
+func LoadEvaluatorOptionsFromEvaluator(ctx context.Context, ev Evaluator, source *ModuleSource) (*EvaluatorOptions, error) {
+	var settings ProjectEvaluatorSettings
+	if err := ev.EvaluateModule(ctx, source, &settings); err != nil {
+		return nil, err
+	}
+	if source.Uri.Scheme == "file" {
+		resolveEvaluatorSettingsPaths(&settings, filepath.Dir(filepath.FromSlash(source.Uri.Path)))
+	}
+	opts := &EvaluatorOptions{}
+	applyFromProjectEvaluatorSettings(settings, opts)
+	if err := opts.Err(); err != nil {
+		return nil, err
+	}
+	return opts, nil
+}
+
+// WithEvaluatorOptions replaces the options applied so far with a copy of loaded, such as the
+// options returned by LoadEvaluatorOptions. Options that come after it are applied on top:
+//
+//	NewEvaluator(ctx, WithEvaluatorOptions(loaded), WithResourceReader(reader))
+//
+// Errors reported by earlier options are kept, and so are the effects of WithSandbox and
+// WithCliDefaults.
+var WithEvaluatorOptions = func(loaded *EvaluatorOptions) func(opts *EvaluatorOptions) {
+	return func(opts *EvaluatorOptions) {
+		errs, sandbox, cliDefaults := opts.errs, opts.sandbox, opts.cliDefaults
+		*opts = *loaded
+		// later options must not modify loaded through shared maps and slices.
+		opts.Properties = maps.Clone(loaded.Properties)
+		opts.Env = maps.Clone(loaded.Env)
+		opts.ModulePaths = slices.Clone(loaded.ModulePaths)
+		opts.AllowedModules = slices.Clone(loaded.AllowedModules)
+		opts.AllowedResources = slices.Clone(loaded.AllowedResources)
+		opts.ResourceReaders = slices.Clone(loaded.ResourceReaders)
+		opts.ModuleReaders = slices.Clone(loaded.ModuleReaders)
+		opts.ExternalModuleReaders = maps.Clone(loaded.ExternalModuleReaders)
+		opts.ExternalResourceReaders = maps.Clone(loaded.ExternalResourceReaders)
+		opts.Interceptors = slices.Clone(loaded.Interceptors)
+		opts.MetricsRecorders = slices.Clone(loaded.MetricsRecorders)
+		opts.errs = append(errs, loaded.errs...)
+		if sandbox != nil {
+			opts.sandbox = sandbox
+		}
+		opts.cliDefaults = cliDefaults || loaded.cliDefaults
+	}
+}
+
+// resolveEvaluatorSettingsPaths resolves the relative paths within settings against dir.
+func resolveEvaluatorSettingsPaths(settings *ProjectEvaluatorSettings, dir string) {
+	resolve := func(path string) string {
+		if path == "" || filepath.IsAbs(path) {
+			return path
+		}
+		return filepath.Join(dir, path)
+	}
+	settings.RootDir = resolve(settings.RootDir)
+	settings.ModuleCacheDir = resolve(settings.ModuleCacheDir)
+	if settings.ModulePath != nil {
+		modulePath := make([]string, len(settings.ModulePath))
+		for i, path := range settings.ModulePath {
+			modulePath[i] = resolve(path)
+		}
+		settings.ModulePath = modulePath
+	}
+}
+
+// RenderEvaluatorSettings renders the options as a Pkl module that amends `pkl:EvaluatorSettings`.
+//
+// Options that cannot be expressed in Pkl, such as readers implemented in Go, loggers and
+// interceptors, are left out.
+// An empty CacheDir is rendered as `noCache = true`.
+//
+// The body of the module can also be used as the `evaluatorSettings` of a PklProject.
+func (e *EvaluatorOptions) RenderEvaluatorSettings() string {
+	w := &pklWriter{}
+	w.line(`amends "pkl:EvaluatorSettings"`)
+	w.line("")
+	w.stringMap("externalProperties", e.Properties)
+	w.stringMap("env", e.Env)
+	w.listing("allowedModules", slices.DeleteFunc(slices.Clone(e.AllowedModules), func(pattern string) bool {
+		// added to every evaluator by NewEvaluator.
+		return pattern == "repl:text"
+	}))
+	w.listing("allowedResources", e.AllowedResources)
+	w.listing("modulePath", e.ModulePaths)
+	if e.Timeout > 0 {
+		w.line("timeout = " + pklDuration(e.Timeout))
+	}
+	if e.CacheDir == "" {
+		w.line("noCache = true")
+	} else {
+		w.line("moduleCacheDir = " + pklString(e.CacheDir))
+	}
+	if e.RootDir != "" {
+		w.line("rootDir = " + pklString(e.RootDir))
+	}
+	if h := e.Http; h != nil && (h.Proxy != nil || h.Rewrites != nil || h.Headers != nil) {
+		w.open("http")
+		if h.Proxy != nil {
+			w.open("proxy")
+			if h.Proxy.Address != "" {
+				w.line("address = " + pklString(h.Proxy.Address))
+			}
+			w.listing("noProxy", h.Proxy.NoProxy)
+			w.close()
+		}
+		w.stringMap("rewrites", h.Rewrites)
+		if h.Headers != nil {
+			w.open("headers")
+			for _, pattern := range slices.Sorted(maps.Keys(h.Headers)) {
+				w.open("[" + pklString(pattern) + "]")
+				headers := h.Headers[pattern]
+				for _, name := range slices.Sorted(maps.Keys(headers)) {
+					values := headers[name]
+					if len(values) == 1 {
+						w.line("[" + pklString(name) + "] = " + pklString(values[0]))
+					} else {
+						w.listing("["+pklString(name)+"] = new Listing", values)
+					}
+				}
+				w.close()
+			}
+			w.close()
+		}
+		w.close()
+	}
+	w.externalReaders("externalModuleReaders", e.ExternalModuleReaders)
+	w.externalReaders("externalResourceReaders", e.ExternalResourceReaders)
+	if e.TraceMode != "" {
+		w.line("traceMode = " + pklString(string(e.TraceMode)))
+	}
+	return w.String()
+}
+
+// pklWriter writes indented Pkl source code.
+type pklWriter struct {
+	strings.Builder
+	depth int
+}
+
+func (w *pklWriter) line(s string) {
+	w.WriteString(strings.Repeat("  ", w.depth))
+	w.WriteString(s)
+	w.WriteByte('\n')
+}
+
+func (w *pklWriter) open(s string) {
+	w.line(s + " {")
+	w.depth++
+}
+
+func (w *pklWriter) close() {
+	w.depth--
+	w.line("}")
+}
+
+func (w *pklWriter) listing(name string, elements []string) {
+	if elements == nil {
+		return
+	}
+	w.open(name)
+	for _, element := range elements {
+		w.line(pklString(element))
+	}
+	w.close()
+}
+
+func (w *pklWriter) stringMap(name string, entries map[string]string) {
+	if entries == nil {
+		return
+	}
+	w.open(name)
+	for _, key := range slices.Sorted(maps.Keys(entries)) {
+		w.line("[" + pklString(key) + "] = " + pklString(entries[key]))
+	}
+	w.close()
+}
+
+func (w *pklWriter) externalReaders(name string, readers map[string]ExternalReader) {
+	if readers == nil {
+		return
+	}
+	w.open(name)
+	for _, scheme := range slices.Sorted(maps.Keys(readers)) {
+		reader := readers[scheme]
+		w.open("[" + pklString(scheme) + "]")
+		w.line("executable = " + pklString(reader.Executable))
+		w.listing("arguments", reader.Arguments)
+		if reader.WorkingDir != "" {
+			w.line("workingDir = " + pklString(reader.WorkingDir))
+		}
+		w.close()
+	}
+	w.close()
+}
+
+// pklString renders s as a Pkl string literal.
+func pklString(s string) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '\\':
+			sb.WriteString(`\\`)
+		case '"':
+			sb.WriteString(`\"`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\t':
+			sb.WriteString(`\t`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&sb, `\u{%x}`, r)
+			} else {
+				sb.WriteRune(r)
+			}
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
+
+// pklDuration renders d as a Pkl duration literal, in the largest unit that represents it exactly.
+func pklDuration(d time.Duration) string {
+	units := []struct {
+		unit time.Duration
+		name string
+	}{
+		{time.Hour, "h"},
+		{time.Minute, "min"},
+		{time.Second, "s"},
+		{time.Millisecond, "ms"},
+		{time.Microsecond, "us"},
+	}
+	for _, u := range units {
+		if d%u.unit == 0 {
+			return strconv.FormatInt(int64(d/u.unit), 10) + "." + u.name
+		}
+	}
+	return strconv.FormatInt(int64(d), 10) + ".ns"
+}
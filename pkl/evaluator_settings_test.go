@@ -0,0 +1,168 @@
+//===----------------------------------------------------------------------===//
+// Copyright © 2026 Apple Inc. and the Pkl project authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//===----------------------------------------------------------------------===//
+
+package pkl
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/apple/pkl-go/pkl/internal"
+	"github.com/stretchr/testify/assert"
+)
+
+var renderedOptions = &EvaluatorOptions{
+	Properties:       map[string]string{"b": "2", "a": "1"},
+	AllowedModules:   []string{"pkl:", "file:", "repl:text"},
+	AllowedResources: []string{"env:", "prop:"},
+	Timeout:          90 * time.Second,
+	RootDir:          "/srv/config",
+	Http: &Http{
+		Proxy:    &Proxy{Address: "http://proxy:8080", NoProxy: []string{"localhost"}},
+		Rewrites: map[string]string{"https://example.com/": "https://mirror.example.com/"},
+		Headers: map[string]http.Header{
+			"**": {"X-Two-Values": []string{"foo", "bar"}, "X-Quote": []string{`say "hi"`}},
+		},
+	},
+	ExternalResourceReaders: map[string]ExternalReader{
+		"secret": {Executable: "secret-reader", Arguments: []string{"--vault"}},
+	},
+	TraceMode: TracePretty,
+}
+
+const renderedSettings = `amends "pkl:EvaluatorSettings"
+
+externalProperties {
+  ["a"] = "1"
+  ["b"] = "2"
+}
+allowedModules {
+  "pkl:"
+  "file:"
+}
+allowedResources {
+  "env:"
+  "prop:"
+}
+timeout = 90.s
+noCache = true
+rootDir = "/srv/config"
+http {
+  proxy {
+    address = "http://proxy:8080"
+    noProxy {
+      "localhost"
+    }
+  }
+  rewrites {
+    ["https://example.com/"] = "https://mirror.example.com/"
+  }
+  headers {
+    ["**"] {
+      ["X-Quote"] = "say \"hi\""
+      ["X-Two-Values"] = new Listing {
+        "foo"
+        "bar"
+      }
+    }
+  }
+}
+externalResourceReaders {
+  ["secret"] {
+    executable = "secret-reader"
+    arguments {
+      "--vault"
+    }
+  }
+}
+traceMode = "pretty"
+`
+
+func TestEvaluatorOptions_RenderEvaluatorSettings(t *testing.T) {
+	assert.Equal(t, renderedSettings, renderedOptions.RenderEvaluatorSettings())
+	assert.Equal(t, "amends \"pkl:EvaluatorSettings\"\n\nnoCache = true\n", (&EvaluatorOptions{}).RenderEvaluatorSettings())
+}
+
+func TestPklDuration(t *testing.T) {
+	assert.Equal(t, "5.min", pklDuration(5*time.Minute))
+	assert.Equal(t, "2.h", pklDuration(2*time.Hour))
+	assert.Equal(t, "1500.ms", pklDuration(1500*time.Millisecond))
+	assert.Equal(t, "7.ns", pklDuration(7))
+}
+
+func TestLoadEvaluatorOptions(t *testing.T) {
+	manager := NewEvaluatorManager()
+	defer func() { assert.NoError(t, manager.Close()) }()
+	version, err := manager.(*evaluatorManager).getVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version.IsLessThan(internal.PklVersion0_32) {
+		t.SkipNow()
+	}
+	tempDir := t.TempDir()
+	writeFile(t, tempDir+"/settings.pkl", `
+amends "pkl:EvaluatorSettings"
+
+rootDir = "sandbox"
+noCache = true
+allowedModules { "pkl:"; "file:" }
+env { ["HOME"] = "/nonexistent" }
+timeout = 30.s
+`)
+	opts, err := LoadEvaluatorOptions(context.Background(), FileSource(tempDir, "settings.pkl"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, filepath.Join(tempDir, "sandbox"), opts.RootDir)
+	assert.Equal(t, "", opts.CacheDir)
+	assert.Equal(t, []string{"pkl:", "file:"}, opts.AllowedModules)
+	assert.Equal(t, map[string]string{"HOME": "/nonexistent"}, opts.Env)
+	assert.Equal(t, 30*time.Second, opts.Timeout)
+
+	// rendered settings load back into the same options.
+	rendered := renderedOptions.RenderEvaluatorSettings()
+	writeFile(t, tempDir+"/rendered.pkl", rendered)
+	loaded, err := LoadEvaluatorOptions(context.Background(), FileSource(tempDir, "rendered.pkl"))
+	if assert.NoError(t, err) {
+		assert.Equal(t, rendered, loaded.RenderEvaluatorSettings())
+	}
+}
+
+func TestWithEvaluatorOptions(t *testing.T) {
+	loaded := &EvaluatorOptions{
+		Env:            map[string]string{"HOME": "/nonexistent"},
+		AllowedModules: []string{"pkl:"},
+		Timeout:        30 * time.Second,
+	}
+	opts, err := buildEvaluatorOptions(internal.PklVersion0_32, WithCliDefaults, WithEvaluatorOptions(loaded), func(opts *EvaluatorOptions) {
+		opts.Env["FOO"] = "bar"
+		opts.AllowedModules = append(opts.AllowedModules, "file:")
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, map[string]string{"HOME": "/nonexistent", "FOO": "bar"}, opts.Env)
+	assert.Equal(t, []string{"pkl:", "file:", "repl:text"}, opts.AllowedModules)
+	assert.Equal(t, 30*time.Second, opts.Timeout)
+	assert.True(t, opts.cliDefaults)
+	// later options do not modify the loaded options.
+	assert.Equal(t, map[string]string{"HOME": "/nonexistent"}, loaded.Env)
+	assert.Equal(t, []string{"pkl:"}, loaded.AllowedModules)
+}
@@ -649,7 +649,9 @@ class Bar {
 				},
 			}
 		})
-		assert.ErrorContains(t, err, "http options are not supported on Pkl versions lower than 0.26")
+		var unsupportedErr *UnsupportedFeatureError
+		assert.ErrorAs(t, err, &unsupportedErr)
+		assert.ErrorContains(t, err, "EvaluatorOptions.Http requires Pkl >=0.26.0")
 	})
 
 	t.Run("WithProjectEvaluatorSettings refuses to load module past configured root dir", func(t *testing.T) {
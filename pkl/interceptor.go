@@ -0,0 +1,163 @@
+//===----------------------------------------------------------------------===//
+// Copyright © 2026 Apple Inc. and the Pkl project authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//===----------------------------------------------------------------------===//
+
+package pkl
+
+import (
+	"context"
+	"net/url"
+)
+
+// Interceptor wraps the traffic of an evaluator.
+//
+// Each field is optional, and receives the next step of the chain, which it is expected to call
+// exactly once, unless it wants to short-circuit the call.
+// Interceptors can be used to add logging, metrics, auditing, retries, or fault injection.
+//
+// Interceptors are registered through WithInterceptor on an evaluator, or through
+// WithManagerInterceptor for every evaluator of an EvaluatorManager.
+// The manager's interceptors run first, followed by the evaluator's, in the order that they are
+// registered.
+type Interceptor struct {
+	// Evaluate wraps every evaluation.
+	//
+	// Every Evaluate* method of Evaluator is served by a call to Evaluate.
+	Evaluate func(ctx context.Context, req EvaluateRequest, next EvaluateHandler) ([]byte, error)
+
+	// Log wraps every message logged by Pkl, before it is handed to the evaluator's Logger.
+	Log func(msg LogMessage, next LogHandler)
+
+	// Reader wraps every call from Pkl into a ModuleReader or ResourceReader of the evaluator.
+	Reader func(req ReaderRequest, next ReaderHandler) ReaderResponse
+}
+
+// EvaluateHandler is a step of the chain of an Interceptor's Evaluate.
+type EvaluateHandler func(ctx context.Context, req EvaluateRequest) ([]byte, error)
+
+// LogHandler is a step of the chain of an Interceptor's Log.
+type LogHandler func(msg LogMessage)
+
+// ReaderHandler is a step of the chain of an Interceptor's Reader.
+type ReaderHandler func(req ReaderRequest) ReaderResponse
+
+// EvaluateRequest is an evaluation of an expression on a module.
+type EvaluateRequest struct {
+	// Source is the module to evaluate.
+	Source *ModuleSource
+
+	// Expr is the expression to evaluate, or the empty string to evaluate the whole module.
+	Expr string
+}
+
+// LogLevel is the level of a LogMessage.
+type LogLevel int
+
+const (
+	// LogLevelTrace is the level of messages emitted by `trace()`.
+	LogLevelTrace LogLevel = 0
+	// LogLevelWarn is the level of warnings, for example when using a deprecated member.
+	LogLevelWarn LogLevel = 1
+)
+
+// LogMessage is a message logged by Pkl during evaluation.
+type LogMessage struct {
+	Level    LogLevel
+	Message  string
+	FrameUri string
+}
+
+// ReaderOperation is an operation that Pkl asks a ModuleReader or ResourceReader to perform.
+type ReaderOperation string
+
+const (
+	ReadResource  ReaderOperation = "readResource"
+	ReadModule    ReaderOperation = "readModule"
+	ListResources ReaderOperation = "listResources"
+	ListModules   ReaderOperation = "listModules"
+)
+
+// ReaderRequest is a call from Pkl into a reader.
+type ReaderRequest struct {
+	Operation ReaderOperation
+	Uri       url.URL
+}
+
+// ReaderResponse is the result of a ReaderRequest.
+type ReaderResponse struct {
+	// Contents is the contents of the resource or module, for read operations.
+	Contents []byte
+
+	// PathElements are the listed elements, for list operations.
+	PathElements []PathElement
+
+	// Err is the error that occurred, if any.
+	//
+	// Reading a resource may fail with ResourceNotFound.
+	Err error
+}
+
+// WithInterceptor adds an interceptor to the evaluator.
+var WithInterceptor = func(interceptor Interceptor) func(opts *EvaluatorOptions) {
+	return func(opts *EvaluatorOptions) {
+		opts.Interceptors = append(opts.Interceptors, interceptor)
+	}
+}
+
+// WithManagerInterceptor adds an interceptor to every evaluator created by the EvaluatorManager.
+var WithManagerInterceptor = func(interceptor Interceptor) func(opts *EvaluatorManagerOptions) {
+	return func(opts *EvaluatorManagerOptions) {
+		opts.Interceptors = append(opts.Interceptors, interceptor)
+	}
+}
+
+func chainEvaluate(interceptors []Interceptor, final EvaluateHandler) EvaluateHandler {
+	handler := final
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		if intercept := interceptors[i].Evaluate; intercept != nil {
+			next := handler
+			handler = func(ctx context.Context, req EvaluateRequest) ([]byte, error) {
+				return intercept(ctx, req, next)
+			}
+		}
+	}
+	return handler
+}
+
+func chainLog(interceptors []Interceptor, final LogHandler) LogHandler {
+	handler := final
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		if intercept := interceptors[i].Log; intercept != nil {
+			next := handler
+			handler = func(msg LogMessage) {
+				intercept(msg, next)
+			}
+		}
+	}
+	return handler
+}
+
+func chainReader(interceptors []Interceptor, final ReaderHandler) ReaderHandler {
+	handler := final
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		if intercept := interceptors[i].Reader; intercept != nil {
+			next := handler
+			handler = func(req ReaderRequest) ReaderResponse {
+				return intercept(req, next)
+			}
+		}
+	}
+	return handler
+}
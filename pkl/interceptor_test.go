@@ -0,0 +1,142 @@
+//===----------------------------------------------------------------------===//
+// Copyright © 2026 Apple Inc. and the Pkl project authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//===----------------------------------------------------------------------===//
+
+package pkl_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/apple/pkl-go/pkl"
+	"github.com/apple/pkl-go/pkl/pkltest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInterceptor_Evaluate(t *testing.T) {
+	server := pkltest.NewServer()
+	server.HandleEvaluate(func(call *pkltest.Call) ([]byte, error) {
+		return pkltest.Encode(call.Expr)
+	})
+	manager := newServerManager(t, server, pkl.WithManagerInterceptor(pkl.Interceptor{
+		Evaluate: func(ctx context.Context, req pkl.EvaluateRequest, next pkl.EvaluateHandler) ([]byte, error) {
+			out, err := next(ctx, req)
+			return append([]byte("manager:"), out...), err
+		},
+	}))
+
+	var seen []string
+	ev, err := manager.NewEvaluator(context.Background(), pkl.WithInterceptor(pkl.Interceptor{
+		Evaluate: func(ctx context.Context, req pkl.EvaluateRequest, next pkl.EvaluateHandler) ([]byte, error) {
+			seen = append(seen, req.Expr)
+			if req.Expr == "blocked" {
+				return nil, errors.New("blocked")
+			}
+			return next(ctx, req)
+		},
+	}))
+	if !assert.NoError(t, err) {
+		return
+	}
+	out, err := ev.EvaluateExpressionRaw(context.Background(), pkl.TextSource("foo = 1"), "foo")
+	assert.NoError(t, err)
+	assert.Equal(t, append([]byte("manager:"), pkltest.MustEncode("foo")...), out)
+	assert.Equal(t, 1, server.Evaluations())
+
+	_, err = ev.EvaluateExpressionRaw(context.Background(), pkl.TextSource("foo = 1"), "blocked")
+	assert.EqualError(t, err, "blocked")
+	assert.Equal(t, 1, server.Evaluations())
+	assert.Equal(t, []string{"foo", "blocked"}, seen)
+}
+
+func TestInterceptor_Reader(t *testing.T) {
+	server := pkltest.NewServer()
+	server.HandleEvaluate(func(call *pkltest.Call) ([]byte, error) {
+		contents, err := call.ReadResource("changing:" + call.Expr)
+		if err != nil {
+			return nil, err
+		}
+		return pkltest.Encode(string(contents))
+	})
+	manager := newServerManager(t, server)
+
+	var requests []pkl.ReaderRequest
+	ev, err := manager.NewEvaluator(context.Background(),
+		pkl.WithResourceReader(&changingResourceReader{}),
+		pkl.WithInterceptor(pkl.Interceptor{
+			Reader: func(req pkl.ReaderRequest, next pkl.ReaderHandler) pkl.ReaderResponse {
+				requests = append(requests, req)
+				if req.Uri.Opaque == "missing" {
+					return pkl.ReaderResponse{Err: pkl.ResourceNotFound}
+				}
+				resp := next(req)
+				resp.Contents = bytes.ToUpper(resp.Contents)
+				return resp
+			},
+		}),
+	)
+	if !assert.NoError(t, err) {
+		return
+	}
+	out, err := ev.EvaluateExpressionRaw(context.Background(), pkl.TextSource("foo = 1"), "secret")
+	assert.NoError(t, err)
+	assert.Equal(t, pkltest.MustEncode("CONTENTS"), out)
+	_, err = ev.EvaluateExpressionRaw(context.Background(), pkl.TextSource("foo = 1"), "missing")
+	assert.ErrorContains(t, err, pkl.ResourceNotFound.Error())
+	if assert.Len(t, requests, 2) {
+		assert.Equal(t, pkl.ReadResource, requests[0].Operation)
+		assert.Equal(t, "changing:secret", requests[0].Uri.String())
+	}
+}
+
+func TestInterceptor_Log(t *testing.T) {
+	server := pkltest.NewServer()
+	server.HandleEvaluate(func(call *pkltest.Call) ([]byte, error) {
+		if err := call.Log(pkl.LogLevelWarn, "dropped", "repl:text"); err != nil {
+			return nil, err
+		}
+		if err := call.Log(pkl.LogLevelTrace, "kept", "repl:text"); err != nil {
+			return nil, err
+		}
+		return pkltest.Encode(nil)
+	})
+	manager := newServerManager(t, server)
+
+	var buf bytes.Buffer
+	logged := make(chan pkl.LogMessage, 2)
+	ev, err := manager.NewEvaluator(context.Background(),
+		func(opts *pkl.EvaluatorOptions) { opts.Logger = pkl.NewLogger(&buf) },
+		pkl.WithInterceptor(pkl.Interceptor{
+			Log: func(msg pkl.LogMessage, next pkl.LogHandler) {
+				logged <- msg
+				if msg.Level == pkl.LogLevelTrace {
+					next(msg)
+				}
+			},
+		}),
+	)
+	if !assert.NoError(t, err) {
+		return
+	}
+	// the logs are handled before the response to the evaluation.
+	_, err = ev.EvaluateExpressionRaw(context.Background(), pkl.TextSource("foo = 1"), "foo")
+	assert.NoError(t, err)
+	assert.Equal(t, pkl.LogMessage{Level: pkl.LogLevelWarn, Message: "dropped", FrameUri: "repl:text"}, <-logged)
+	assert.Equal(t, pkl.LogMessage{Level: pkl.LogLevelTrace, Message: "kept", FrameUri: "repl:text"}, <-logged)
+	assert.NotContains(t, buf.String(), "dropped")
+	assert.Contains(t, buf.String(), "kept")
+}
@@ -17,8 +17,6 @@
 package msgapi
 
 import (
-	"fmt"
-
 	"github.com/vmihailenco/msgpack/v5"
 )
 
@@ -39,6 +37,7 @@ var (
 	_ IncomingMessage = (*ListResources)(nil)
 	_ IncomingMessage = (*ListModules)(nil)
 	_ IncomingMessage = (*CloseExternalProcess)(nil)
+	_ IncomingMessage = (*Unknown)(nil)
 )
 
 type CreateEvaluatorResponse struct {
@@ -117,8 +116,17 @@ type CloseExternalProcess struct {
 	incomingMessageImpl
 }
 
+// Unknown is a message whose code is not known to this version of pkl-go.
+//
+// Its body is skipped, so that newer versions of Pkl can introduce new messages.
+type Unknown struct {
+	incomingMessageImpl
+
+	Code int
+}
+
 func Decode(decoder *msgpack.Decoder) (IncomingMessage, error) {
-	_, err := decoder.DecodeArrayLen()
+	n, err := decoder.DecodeArrayLen()
 	if err != nil {
 		return nil, err
 	}
@@ -158,6 +166,11 @@ func Decode(decoder *msgpack.Decoder) (IncomingMessage, error) {
 		var resp CloseExternalProcess
 		return &resp, decoder.Decode(&resp)
 	default:
-		panic(fmt.Sprintf("Unknown code: %d", c))
+		for i := 1; i < n; i++ {
+			if err = decoder.Skip(); err != nil {
+				return nil, err
+			}
+		}
+		return &Unknown{Code: c}, nil
 	}
 }
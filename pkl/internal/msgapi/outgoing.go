@@ -37,7 +37,7 @@ var (
 	_ OutgoingMessage = (*ListModulesResponse)(nil)
 )
 
-func packMessage(msg OutgoingMessage, code int) ([]byte, error) {
+func packMessage(msg any, code int) ([]byte, error) {
 	enc := msgpack.NewEncoder(nil)
 	var buf bytes.Buffer
 	enc.Reset(&buf)
@@ -67,21 +67,20 @@ type ModuleReader struct {
 }
 
 type CreateEvaluator struct {
-	RequestId              int64                `msgpack:"requestId"`
-	ResourceReaders        []*ResourceReader    `msgpack:"clientResourceReaders,omitempty"`
-	ModuleReaders          []*ModuleReader      `msgpack:"clientModuleReaders,omitempty"`
-	ExternalReaderCommands [][]string           `msgpack:"externalReaderCommands,omitempty"`
-	ModulePaths            []string             `msgpack:"modulePaths,omitempty"`
-	Env                    map[string]string    `msgpack:"env,omitempty"`
-	Properties             map[string]string    `msgpack:"properties,omitempty"`
-	OutputFormat           string               `msgpack:"outputFormat,omitempty"`
-	AllowedModules         []string             `msgpack:"allowedModules,omitempty"`
-	AllowedResources       []string             `msgpack:"allowedResources,omitempty"`
-	RootDir                string               `msgpack:"rootDir,omitempty"`
-	CacheDir               string               `msgpack:"cacheDir,omitempty"`
-	Project                *ProjectOrDependency `msgpack:"project,omitempty"`
-	Http                   *Http                `msgpack:"http,omitempty"`
-	// Intentionally not used right now. Go has `context.WithTimeout` which is a more canonical way to handle timeouts.
+	RequestId               int64                      `msgpack:"requestId"`
+	ResourceReaders         []*ResourceReader          `msgpack:"clientResourceReaders,omitempty"`
+	ModuleReaders           []*ModuleReader            `msgpack:"clientModuleReaders,omitempty"`
+	ExternalReaderCommands  [][]string                 `msgpack:"externalReaderCommands,omitempty"`
+	ModulePaths             []string                   `msgpack:"modulePaths,omitempty"`
+	Env                     map[string]string          `msgpack:"env,omitempty"`
+	Properties              map[string]string          `msgpack:"properties,omitempty"`
+	OutputFormat            string                     `msgpack:"outputFormat,omitempty"`
+	AllowedModules          []string                   `msgpack:"allowedModules,omitempty"`
+	AllowedResources        []string                   `msgpack:"allowedResources,omitempty"`
+	RootDir                 string                     `msgpack:"rootDir,omitempty"`
+	CacheDir                string                     `msgpack:"cacheDir,omitempty"`
+	Project                 *ProjectOrDependency       `msgpack:"project,omitempty"`
+	Http                    *Http                      `msgpack:"http,omitempty"`
 	TimeoutSeconds          int64                      `msgpack:"timeoutSeconds,omitempty"`
 	ExternalModuleReaders   map[string]*ExternalReader `msgpack:"externalModuleReaders,omitempty"`
 	ExternalResourceReaders map[string]*ExternalReader `msgpack:"externalResourceReaders,omitempty"`
@@ -111,7 +110,7 @@ type Proxy struct {
 type ExternalReader struct {
 	Executable string   `msgpack:"executable"`
 	Arguments  []string `msgpack:"arguments,omitempty"`
-	WorkingDir string   `msgpack:"workingDir,omitempty'`
+	WorkingDir string   `msgpack:"workingDir,omitempty"`
 }
 
 type Checksums struct {
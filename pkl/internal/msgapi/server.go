@@ -0,0 +1,84 @@
+//===----------------------------------------------------------------------===//
+// Copyright © 2026 Apple Inc. and the Pkl project authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//===----------------------------------------------------------------------===//
+
+package msgapi
+
+import (
+	"fmt"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// This file holds the server side of the message passing API, which encodes incoming messages,
+// and decodes outgoing messages. It is used to fake a Pkl server.
+
+// Encode encodes a message that is sent by the server.
+func Encode(msg IncomingMessage) ([]byte, error) {
+	var code int
+	switch msg := msg.(type) {
+	case *CreateEvaluatorResponse:
+		code = codeNewEvaluatorResponse
+	case *EvaluateResponse:
+		code = codeEvaluateResponse
+	case *Log:
+		code = codeEvaluateLog
+	case *ReadResource:
+		code = codeEvaluateRead
+	case *ReadModule:
+		code = codeEvaluateReadModule
+	case *ListResources:
+		code = codeListResourcesRequest
+	case *ListModules:
+		code = codeListModulesRequest
+	case *Unknown:
+		code = msg.Code
+	default:
+		return nil, fmt.Errorf("cannot encode message of type %T", msg)
+	}
+	return packMessage(msg, code)
+}
+
+// DecodeOutgoing decodes a message that is sent by the client.
+func DecodeOutgoing(decoder *msgpack.Decoder) (OutgoingMessage, error) {
+	_, err := decoder.DecodeArrayLen()
+	if err != nil {
+		return nil, err
+	}
+	c, err := decoder.DecodeInt()
+	if err != nil {
+		return nil, err
+	}
+	var msg OutgoingMessage
+	switch c {
+	case codeNewEvaluator:
+		msg = &CreateEvaluator{}
+	case codeCloseEvaluator:
+		msg = &CloseEvaluator{}
+	case codeEvaluate:
+		msg = &Evaluate{}
+	case codeEvaluateReadResponse:
+		msg = &ReadResourceResponse{}
+	case codeEvaluateReadModuleResponse:
+		msg = &ReadModuleResponse{}
+	case codeListResourcesResponse:
+		msg = &ListResourcesResponse{}
+	case codeListModulesResponse:
+		msg = &ListModulesResponse{}
+	default:
+		return nil, fmt.Errorf("unknown message code: %#x", c)
+	}
+	return msg, decoder.Decode(msg)
+}
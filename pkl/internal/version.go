@@ -203,3 +203,75 @@ func ParseSemver(s string) (*Semver, error) {
 		build:      matched[5],
 	}, nil
 }
+
+// VersionConstraint is a set of comparisons that a version must satisfy, such as ">=0.29, <0.33".
+type VersionConstraint struct {
+	source  string
+	clauses []versionClause
+}
+
+type versionClause struct {
+	operator string
+	version  *Semver
+}
+
+var versionOperators = []string{">=", "<=", "!=", ">", "<", "="}
+
+// ParseVersionConstraint parses a comma-separated list of comparisons.
+//
+// Each comparison is an operator (one of >=, <=, >, <, =, !=) followed by a version. A version
+// without an operator must match exactly, and a version with only a major and minor component is
+// treated as having patch 0.
+func ParseVersionConstraint(s string) (*VersionConstraint, error) {
+	constraint := &VersionConstraint{source: s}
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		operator := "="
+		for _, op := range versionOperators {
+			if strings.HasPrefix(part, op) {
+				operator = op
+				part = strings.TrimSpace(strings.TrimPrefix(part, op))
+				break
+			}
+		}
+		if strings.Count(part, ".") == 1 {
+			part += ".0"
+		}
+		version, err := ParseSemver(part)
+		if err != nil || version.String() != part {
+			return nil, fmt.Errorf("invalid version constraint %q", s)
+		}
+		constraint.clauses = append(constraint.clauses, versionClause{operator: operator, version: version})
+	}
+	return constraint, nil
+}
+
+// Matches tells if version satisfies every comparison of the constraint.
+func (c *VersionConstraint) Matches(version *Semver) bool {
+	for _, clause := range c.clauses {
+		cmp := version.CompareTo(clause.version)
+		var ok bool
+		switch clause.operator {
+		case ">=":
+			ok = cmp >= 0
+		case "<=":
+			ok = cmp <= 0
+		case "!=":
+			ok = cmp != 0
+		case ">":
+			ok = cmp > 0
+		case "<":
+			ok = cmp < 0
+		default:
+			ok = cmp == 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *VersionConstraint) String() string {
+	return c.source
+}
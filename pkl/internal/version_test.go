@@ -50,3 +50,27 @@ func TestCompareSemverVersions(t *testing.T) {
 	assert.Equal(t, 1, compareVersions("2.0.0-1.2.3.4", "2.0.0-1.2.3"))
 	assert.Equal(t, 0, compareVersions("2.0.0+foo", "2.0.0+bar"))
 }
+
+func TestVersionConstraint(t *testing.T) {
+	matches := func(constraint string, version string) bool {
+		c, err := ParseVersionConstraint(constraint)
+		if !assert.NoError(t, err) {
+			return false
+		}
+		return c.Matches(MustParseSemver(version))
+	}
+	assert.True(t, matches(">=0.29", "0.29.0"))
+	assert.True(t, matches(">=0.29", "0.30.1"))
+	assert.False(t, matches(">=0.29", "0.28.2"))
+	assert.True(t, matches(">=0.28, <0.31", "0.30.9"))
+	assert.False(t, matches(">=0.28, <0.31", "0.31.0"))
+	assert.True(t, matches("0.30.2", "0.30.2"))
+	assert.False(t, matches("=0.30.2", "0.30.1"))
+	assert.True(t, matches("!=0.30.1", "0.30.2"))
+	assert.True(t, matches("> 0.30.1, <= 0.32", "0.32.0"))
+
+	for _, invalid := range []string{"", ">=", "~0.29", ">=0.29,", "0.29.0 garbage", ">= x.y.z"} {
+		_, err := ParseVersionConstraint(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
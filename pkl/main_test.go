@@ -0,0 +1,118 @@
+//===----------------------------------------------------------------------===//
+// Copyright © 2026 Apple Inc. and the Pkl project authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//===----------------------------------------------------------------------===//
+
+package pkl_test
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apple/pkl-go/pkl"
+	"github.com/apple/pkl-go/pkl/pkltest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMain(m *testing.M) {
+	if len(os.Args) > 1 && os.Args[1] == "fake-pkl" {
+		fakePkl(os.Args[2:])
+		return
+	}
+	os.Exit(m.Run())
+}
+
+// fakePkl runs the test binary as a stand-in for the pkl CLI, serving a pkltest.Server over
+// standard input and output.
+//
+// Evaluations of the expression "hang" never complete, evaluations of "exit:<n>" make the process
+// exit with code 3 once n evaluations hang, evaluations of "pid" evaluate to the process ID, and
+// other expressions evaluate to themselves.
+// With the argument --exit-after=<duration>, the process also exits with code 3 after duration.
+func fakePkl(args []string) {
+	if slices.Contains(args, "--version") {
+		fmt.Println("Pkl 0.32.0 (fake)")
+		os.Exit(0)
+	}
+	for _, arg := range args {
+		if after, ok := strings.CutPrefix(arg, "--exit-after="); ok {
+			delay, err := time.ParseDuration(after)
+			if err != nil {
+				_, _ = fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			time.AfterFunc(delay, func() { os.Exit(3) })
+		}
+	}
+	server := pkltest.NewServer()
+	hangs := make(chan struct{}, 100)
+	server.HandleEvaluate(func(call *pkltest.Call) ([]byte, error) {
+		if call.Expr == "hang" {
+			hangs <- struct{}{}
+			select {}
+		}
+		if n, ok := strings.CutPrefix(call.Expr, "exit:"); ok {
+			count, err := strconv.Atoi(n)
+			if err != nil {
+				return nil, err
+			}
+			for range count {
+				<-hangs
+			}
+			_, _ = fmt.Fprintln(os.Stderr, "fake-pkl: exiting")
+			os.Exit(3)
+		}
+		if call.Expr == "pid" {
+			return pkltest.Encode(os.Getpid())
+		}
+		return pkltest.Encode(call.Expr)
+	})
+	conn, err := server.Transport().Connect()
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	go func() {
+		_, _ = io.Copy(conn, os.Stdin)
+		os.Exit(0)
+	}()
+	_, _ = io.Copy(os.Stdout, conn)
+	os.Exit(0)
+}
+
+// newFakePklManager creates an EvaluatorManager that spawns fakePkl instead of Pkl.
+func newFakePklManager(opts ...func(options *pkl.EvaluatorManagerOptions)) pkl.EvaluatorManager {
+	opts = append([]func(options *pkl.EvaluatorManagerOptions){
+		pkl.WithPklCommand([]string{os.Args[0], "fake-pkl"}),
+		pkl.WithProcessOptions(pkl.ProcessOptions{Stderr: io.Discard}),
+	}, opts...)
+	return pkl.NewEvaluatorManagerWithOptions(opts...)
+}
+
+// newServerManager creates an EvaluatorManager that connects to server, and closes both once the
+// test ends.
+func newServerManager(t *testing.T, server *pkltest.Server, opts ...func(options *pkl.EvaluatorManagerOptions)) pkl.EvaluatorManager {
+	manager := pkl.NewEvaluatorManagerWithTransport(server.Transport(), opts...)
+	t.Cleanup(func() {
+		assert.NoError(t, manager.Close())
+		server.Close()
+	})
+	return manager
+}
@@ -0,0 +1,239 @@
+//===----------------------------------------------------------------------===//
+// Copyright © 2026 Apple Inc. and the Pkl project authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//===----------------------------------------------------------------------===//
+
+package pkl
+
+import (
+	"bytes"
+	"sync"
+
+	"github.com/apple/pkl-go/pkl/internal"
+	"github.com/apple/pkl-go/pkl/internal/msgapi"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Message is a single message of Pkl's message passing API, encoded as MessagePack like
+// `pkl server` encodes it: an array of the message type code and the message body.
+//
+// See https://pkl-lang.org/main/current/bindings-specification/message-passing-api.html.
+type Message []byte
+
+// MessageTransport connects an EvaluatorManager to Pkl at the level of individual messages.
+//
+// It mirrors the contract of the built-in ways of running Pkl within pkl-go, and suits
+// backends that already exchange whole messages, such as a binding of Pkl as a native library, or
+// a replay of recorded traffic. Backends that provide a byte stream, such as a connection to a
+// server, are simpler to plug in as a Transport.
+//
+// The EvaluatorManager takes care of encoding and decoding messages, and of dispatching them to
+// evaluators and readers. The channels are owned by the MessageTransport, and must be the same on
+// every call.
+type MessageTransport interface {
+	// Init starts Pkl.
+	//
+	// It is called once, before the first evaluator of the EvaluatorManager is created.
+	Init() error
+
+	// Deinit stops Pkl, and closes the channels returned by InChan, OutChan and ClosedChan.
+	//
+	// It is called once, when the EvaluatorManager is closed. By then, the manager no longer sends
+	// to OutChan, but it may have stopped receiving from InChan and ClosedChan, so sends to those
+	// must not block Deinit.
+	Deinit() error
+
+	// InChan returns the channel of messages sent by Pkl.
+	InChan() chan Message
+
+	// OutChan returns the channel of messages to send to Pkl.
+	OutChan() chan Message
+
+	// ClosedChan returns the channel that receives an error if Pkl stops unexpectedly, for
+	// example because it exited. The EvaluatorManager is then closed with that error.
+	ClosedChan() chan error
+
+	// GetVersion returns the version of Pkl, for example "0.30.0".
+	GetVersion() (string, error)
+}
+
+// NewEvaluatorManagerWithMessageTransport creates a new EvaluatorManager that exchanges messages
+// with Pkl through the given transport.
+//
+// Options that control how Pkl is spawned, such as WithPklCommand, have no effect. Because a
+// MessageTransport cannot be restarted, WithSupervisor has no effect either.
+func NewEvaluatorManagerWithMessageTransport(transport MessageTransport, opts ...func(options *EvaluatorManagerOptions)) EvaluatorManager {
+	o := EvaluatorManagerOptions{}
+	for _, f := range opts {
+		f(&o)
+	}
+	return newEvaluatorManager(newMessageTransportEvaluator(transport), o)
+}
+
+// messageTransportEvaluator is the evaluatorManagerImpl that exchanges messages with a
+// MessageTransport, encoding and decoding them on the way.
+type messageTransportEvaluator struct {
+	in        chan msgapi.IncomingMessage
+	out       chan msgapi.OutgoingMessage
+	closed    chan error
+	transport MessageTransport
+	// closing is closed once deinit starts.
+	closing chan struct{}
+	// relays tracks the goroutines that relay messages from the transport.
+	relays sync.WaitGroup
+	// sending is closed once the goroutine that relays messages to the transport has stopped.
+	sending chan struct{}
+
+	// mu guards started and version, and orders init against deinit.
+	mu      sync.Mutex
+	started bool
+	version *internal.Semver
+}
+
+func newMessageTransportEvaluator(transport MessageTransport) *messageTransportEvaluator {
+	return &messageTransportEvaluator{
+		in:        make(chan msgapi.IncomingMessage),
+		out:       make(chan msgapi.OutgoingMessage),
+		closed:    make(chan error),
+		transport: transport,
+		closing:   make(chan struct{}),
+		sending:   make(chan struct{}),
+	}
+}
+
+var _ evaluatorManagerImpl = (*messageTransportEvaluator)(nil)
+
+func (t *messageTransportEvaluator) inChan() chan msgapi.IncomingMessage {
+	return t.in
+}
+
+func (t *messageTransportEvaluator) outChan() chan msgapi.OutgoingMessage {
+	return t.out
+}
+
+func (t *messageTransportEvaluator) closedChan() chan error {
+	return t.closed
+}
+
+func (t *messageTransportEvaluator) getVersion() (*internal.Semver, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.version != nil {
+		return t.version, nil
+	}
+	version, err := t.transport.GetVersion()
+	if err != nil {
+		return nil, err
+	}
+	semver, err := internal.ParseSemver(version)
+	if err != nil {
+		return nil, err
+	}
+	t.version = semver
+	return semver, nil
+}
+
+func (t *messageTransportEvaluator) init() error {
+	if err := t.transport.Init(); err != nil {
+		return err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	select {
+	case <-t.closing:
+		// the manager was closed while Pkl was starting.
+		_ = t.transport.Deinit()
+		return ErrManagerClosed
+	default:
+	}
+	t.started = true
+	t.relays.Add(2)
+	go t.relayIncomingMessages()
+	go t.relayClosed()
+	go t.relayOutgoingMessages()
+	return nil
+}
+
+// report reports err to the manager, unless deinit has started.
+func (t *messageTransportEvaluator) report(err error) {
+	select {
+	case t.closed <- err:
+	case <-t.closing:
+	}
+}
+
+func (t *messageTransportEvaluator) relayIncomingMessages() {
+	defer t.relays.Done()
+	// keep receiving until the transport closes the channel, so that it never blocks on it.
+	for encoded := range t.transport.InChan() {
+		msg, err := msgapi.Decode(msgpack.NewDecoder(bytes.NewReader(encoded)))
+		if err != nil {
+			t.report(&InternalError{err: err})
+			continue
+		}
+		internal.Debug("Received message: %#v", msg)
+		select {
+		case t.in <- msg:
+		case <-t.closing:
+		}
+	}
+}
+
+func (t *messageTransportEvaluator) relayClosed() {
+	defer t.relays.Done()
+	for err := range t.transport.ClosedChan() {
+		t.report(err)
+	}
+}
+
+func (t *messageTransportEvaluator) relayOutgoingMessages() {
+	defer close(t.sending)
+	for {
+		var msg msgapi.OutgoingMessage
+		select {
+		case msg = <-t.out:
+		case <-t.closing:
+			return
+		}
+		internal.Debug("Sending message: %#v", msg)
+		encoded, err := msg.ToMsgPack()
+		if err != nil {
+			t.report(&InternalError{err: err})
+			continue
+		}
+		select {
+		case t.transport.OutChan() <- encoded:
+		case <-t.closing:
+			return
+		}
+	}
+}
+
+func (t *messageTransportEvaluator) deinit() error {
+	t.mu.Lock()
+	close(t.closing)
+	started := t.started
+	t.mu.Unlock()
+	var err error
+	if started {
+		// the transport closes OutChan, so nothing may be sent to it from now on.
+		<-t.sending
+		err = t.transport.Deinit()
+		t.relays.Wait()
+	}
+	close(t.in)
+	close(t.out)
+	close(t.closed)
+	return err
+}
@@ -0,0 +1,308 @@
+//===----------------------------------------------------------------------===//
+// Copyright © 2026 Apple Inc. and the Pkl project authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//===----------------------------------------------------------------------===//
+
+package pkl
+
+import (
+	"errors"
+	"maps"
+	"math"
+	"sync"
+	"time"
+)
+
+// Stats is a snapshot of the activity of an Evaluator or EvaluatorManager.
+//
+// The statistics of an Evaluator are obtained with EvaluatorStats.
+type Stats struct {
+	// Evaluations is the number of completed evaluations, including failed ones, and those served
+	// from an EvaluationCache.
+	Evaluations int64
+
+	// CacheHits is the number of evaluations that were served from an EvaluationCache, without a
+	// round trip to Pkl.
+	CacheHits int64
+
+	// EvaluationLatency is the distribution of the duration of evaluations.
+	EvaluationLatency LatencyStats
+
+	// Errors is the number of failed evaluations, keyed by the kind of error.
+	//
+	// The kind of an *EvalError is its EvalErrorKind, and other errors are counted as "timeout",
+	// "canceled", "replayMismatch", or "internal".
+	Errors map[string]int64
+
+	// Readers are the statistics of calls from Pkl into readers, keyed by scheme.
+	Readers map[string]ReaderStats
+
+	// BytesReceived is the number of bytes of evaluation results received from Pkl.
+	//
+	// Results served from an EvaluationCache are not counted.
+	BytesReceived int64
+
+	// PendingRequests is the number of evaluations that are awaiting a response from Pkl.
+	PendingRequests int64
+}
+
+// StatsProvider is implemented by evaluators that keep Stats, which includes those returned by
+// NewEvaluator, NewEvaluatorPool and NewReplayEvaluator.
+type StatsProvider interface {
+	// Stats returns the statistics of the evaluator.
+	Stats() Stats
+}
+
+// EvaluatorStats returns the statistics of ev, and false if ev does not keep any.
+func EvaluatorStats(ev Evaluator) (Stats, bool) {
+	if provider, ok := ev.(StatsProvider); ok {
+		return provider.Stats(), true
+	}
+	return Stats{}, false
+}
+
+// ReaderStats are the statistics of the calls into the readers of a scheme.
+type ReaderStats struct {
+	// Calls is the number of completed calls, including failed ones.
+	Calls int64
+
+	// Errors is the number of failed calls.
+	Errors int64
+
+	// Latency is the distribution of the duration of calls.
+	Latency LatencyStats
+}
+
+// LatencyStats is a distribution of durations.
+type LatencyStats struct {
+	Count int64
+	Sum   time.Duration
+	Min   time.Duration
+	Max   time.Duration
+
+	// Buckets are the cumulative counts of durations, in the style of a Prometheus histogram.
+	//
+	// The last bucket's UpperBound is math.MaxInt64, and counts every duration.
+	Buckets []LatencyBucket
+}
+
+// LatencyBucket is a bucket of LatencyStats.
+type LatencyBucket struct {
+	// UpperBound is the inclusive upper bound of the bucket.
+	UpperBound time.Duration
+
+	// Count is the number of durations less than or equal to UpperBound.
+	Count int64
+}
+
+// latencyBuckets are the upper bounds of the buckets of LatencyStats.
+var latencyBuckets = []time.Duration{
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	5 * time.Second,
+	10 * time.Second,
+	math.MaxInt64,
+}
+
+// MetricsRecorder receives the metrics of evaluators as they happen.
+//
+// It can be used to feed the metrics into a collector, like a Prometheus registry.
+// Methods are called concurrently, and must not block.
+type MetricsRecorder interface {
+	// RecordEvaluation is called when an evaluation completes.
+	RecordEvaluation(metric EvaluationMetric)
+
+	// RecordReaderCall is called when a call from Pkl into a reader completes.
+	RecordReaderCall(metric ReaderCallMetric)
+
+	// RecordPendingRequests is called when the number of evaluations awaiting a response from Pkl
+	// changes by delta.
+	RecordPendingRequests(delta int)
+}
+
+// EvaluationMetric describes a completed evaluation.
+type EvaluationMetric struct {
+	Duration time.Duration
+
+	// Bytes is the size of the result.
+	Bytes int
+
+	// Err is the error of the evaluation, if any.
+	Err error
+
+	// ErrorKind categorizes Err, as documented on Stats.Errors.
+	ErrorKind string
+
+	// CacheHit tells if the result was served from an EvaluationCache, without a round trip to
+	// Pkl.
+	CacheHit bool
+}
+
+// ReaderCallMetric describes a completed call from Pkl into a reader.
+type ReaderCallMetric struct {
+	Scheme    string
+	Operation ReaderOperation
+	Duration  time.Duration
+
+	// Err is the error of the call, if any.
+	Err error
+}
+
+// WithMetricsRecorder adds a recorder for the metrics of the evaluator.
+var WithMetricsRecorder = func(recorder MetricsRecorder) func(opts *EvaluatorOptions) {
+	return func(opts *EvaluatorOptions) {
+		opts.MetricsRecorders = append(opts.MetricsRecorders, recorder)
+	}
+}
+
+// WithManagerMetricsRecorder adds a recorder for the metrics of every evaluator created by the
+// EvaluatorManager.
+var WithManagerMetricsRecorder = func(recorder MetricsRecorder) func(opts *EvaluatorManagerOptions) {
+	return func(opts *EvaluatorManagerOptions) {
+		opts.MetricsRecorders = append(opts.MetricsRecorders, recorder)
+	}
+}
+
+func errorKindOf(err error) string {
+	var evalError *EvalError
+	var timeoutError *TimeoutError
+	var canceledError *CanceledError
+	var replayMismatchError *ReplayMismatchError
+	switch {
+	case errors.As(err, &timeoutError):
+		return "timeout"
+	case errors.As(err, &evalError):
+		return string(evalError.Kind)
+	case errors.As(err, &canceledError):
+		return "canceled"
+	case errors.As(err, &replayMismatchError):
+		return "replayMismatch"
+	default:
+		return "internal"
+	}
+}
+
+// statsCollector is a MetricsRecorder that aggregates metrics into Stats.
+type statsCollector struct {
+	mu    sync.Mutex
+	stats Stats
+}
+
+var _ MetricsRecorder = (*statsCollector)(nil)
+
+func (s *statsCollector) RecordEvaluation(metric EvaluationMetric) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stats.Evaluations++
+	s.stats.EvaluationLatency.observe(metric.Duration)
+	if metric.CacheHit {
+		s.stats.CacheHits++
+	} else {
+		s.stats.BytesReceived += int64(metric.Bytes)
+	}
+	if metric.Err != nil {
+		if s.stats.Errors == nil {
+			s.stats.Errors = make(map[string]int64)
+		}
+		s.stats.Errors[metric.ErrorKind]++
+	}
+}
+
+func (s *statsCollector) RecordReaderCall(metric ReaderCallMetric) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stats.Readers == nil {
+		s.stats.Readers = make(map[string]ReaderStats)
+	}
+	readerStats := s.stats.Readers[metric.Scheme]
+	readerStats.Calls++
+	if metric.Err != nil {
+		readerStats.Errors++
+	}
+	readerStats.Latency.observe(metric.Duration)
+	s.stats.Readers[metric.Scheme] = readerStats
+}
+
+func (s *statsCollector) RecordPendingRequests(delta int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stats.PendingRequests += int64(delta)
+}
+
+func (s *statsCollector) snapshot() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats := s.stats
+	stats.EvaluationLatency = stats.EvaluationLatency.clone()
+	stats.Errors = maps.Clone(stats.Errors)
+	stats.Readers = maps.Clone(stats.Readers)
+	for scheme, readerStats := range stats.Readers {
+		readerStats.Latency = readerStats.Latency.clone()
+		stats.Readers[scheme] = readerStats
+	}
+	return stats
+}
+
+func (l *LatencyStats) observe(d time.Duration) {
+	if l.Buckets == nil {
+		l.Buckets = make([]LatencyBucket, len(latencyBuckets))
+		for i, upperBound := range latencyBuckets {
+			l.Buckets[i].UpperBound = upperBound
+		}
+	}
+	if l.Count == 0 || d < l.Min {
+		l.Min = d
+	}
+	l.Max = max(l.Max, d)
+	l.Count++
+	l.Sum += d
+	for i := range l.Buckets {
+		if d <= l.Buckets[i].UpperBound {
+			l.Buckets[i].Count++
+		}
+	}
+}
+
+func (l LatencyStats) clone() LatencyStats {
+	l.Buckets = append([]LatencyBucket(nil), l.Buckets...)
+	return l
+}
+
+// metricsRecorders fans metrics out to several recorders.
+type metricsRecorders []MetricsRecorder
+
+var _ MetricsRecorder = (metricsRecorders)(nil)
+
+func (r metricsRecorders) RecordEvaluation(metric EvaluationMetric) {
+	for _, recorder := range r {
+		recorder.RecordEvaluation(metric)
+	}
+}
+
+func (r metricsRecorders) RecordReaderCall(metric ReaderCallMetric) {
+	for _, recorder := range r {
+		recorder.RecordReaderCall(metric)
+	}
+}
+
+func (r metricsRecorders) RecordPendingRequests(delta int) {
+	for _, recorder := range r {
+		recorder.RecordPendingRequests(delta)
+	}
+}
@@ -0,0 +1,42 @@
+//===----------------------------------------------------------------------===//
+// Copyright © 2026 Apple Inc. and the Pkl project authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//===----------------------------------------------------------------------===//
+
+package pkl
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLatencyStats(t *testing.T) {
+	var l LatencyStats
+	l.observe(2 * time.Millisecond)
+	l.observe(200 * time.Millisecond)
+	assert.Equal(t, int64(2), l.Count)
+	assert.Equal(t, 202*time.Millisecond, l.Sum)
+	assert.Equal(t, 2*time.Millisecond, l.Min)
+	assert.Equal(t, 200*time.Millisecond, l.Max)
+	counts := make(map[time.Duration]int64)
+	for _, bucket := range l.Buckets {
+		counts[bucket.UpperBound] = bucket.Count
+	}
+	assert.Equal(t, int64(0), counts[time.Millisecond])
+	assert.Equal(t, int64(1), counts[5*time.Millisecond])
+	assert.Equal(t, int64(1), counts[100*time.Millisecond])
+	assert.Equal(t, int64(2), counts[500*time.Millisecond])
+}
@@ -0,0 +1,138 @@
+//===----------------------------------------------------------------------===//
+// Copyright © 2026 Apple Inc. and the Pkl project authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//===----------------------------------------------------------------------===//
+
+package pkl_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/apple/pkl-go/pkl"
+	"github.com/apple/pkl-go/pkl/pkltest"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeMetricsRecorder struct {
+	mu          sync.Mutex
+	evaluations []pkl.EvaluationMetric
+	readerCalls []pkl.ReaderCallMetric
+	pending     int
+}
+
+func (r *fakeMetricsRecorder) RecordEvaluation(metric pkl.EvaluationMetric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.evaluations = append(r.evaluations, metric)
+}
+
+func (r *fakeMetricsRecorder) RecordReaderCall(metric pkl.ReaderCallMetric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.readerCalls = append(r.readerCalls, metric)
+}
+
+func (r *fakeMetricsRecorder) RecordPendingRequests(delta int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pending += delta
+}
+
+var _ pkl.MetricsRecorder = (*fakeMetricsRecorder)(nil)
+
+func TestEvaluator_Stats(t *testing.T) {
+	server := pkltest.NewServer()
+	server.HandleEvaluate(func(call *pkltest.Call) ([]byte, error) {
+		if call.Expr == "mismatch" {
+			return nil, pkltest.Errorf("Expected value of type `Int`, but got type `String`.")
+		}
+		if _, err := call.ReadResource("changing:secret"); err != nil {
+			return nil, err
+		}
+		return []byte{0x01, 0x02}, nil
+	})
+	manager := newServerManager(t, server)
+
+	recorder := &fakeMetricsRecorder{}
+	ev, err := manager.NewEvaluator(context.Background(), pkl.WithResourceReader(&changingResourceReader{}), pkl.WithMetricsRecorder(recorder))
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = ev.EvaluateExpressionRaw(context.Background(), pkl.TextSource("foo = 1"), "foo")
+	assert.NoError(t, err)
+	_, err = ev.EvaluateExpressionRaw(context.Background(), pkl.TextSource("foo = 1"), "mismatch")
+	assert.Error(t, err)
+
+	stats, ok := pkl.EvaluatorStats(ev)
+	assert.True(t, ok)
+	assert.Equal(t, int64(2), stats.Evaluations)
+	assert.Equal(t, int64(2), stats.EvaluationLatency.Count)
+	assert.Equal(t, int64(2), stats.EvaluationLatency.Buckets[len(stats.EvaluationLatency.Buckets)-1].Count)
+	assert.Equal(t, map[string]int64{"typeMismatch": 1}, stats.Errors)
+	assert.Equal(t, int64(2), stats.BytesReceived)
+	assert.Equal(t, int64(0), stats.PendingRequests)
+	if assert.Contains(t, stats.Readers, "changing") {
+		assert.Equal(t, int64(1), stats.Readers["changing"].Calls)
+		assert.Equal(t, int64(0), stats.Readers["changing"].Errors)
+	}
+	assert.Equal(t, stats, manager.Stats())
+
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	assert.Len(t, recorder.evaluations, 2)
+	assert.Equal(t, "typeMismatch", recorder.evaluations[1].ErrorKind)
+	if assert.Len(t, recorder.readerCalls, 1) {
+		assert.Equal(t, pkl.ReadResource, recorder.readerCalls[0].Operation)
+	}
+	assert.Equal(t, 0, recorder.pending)
+}
+
+func TestEvaluator_Stats_cacheHits(t *testing.T) {
+	server := pkltest.NewServer()
+	server.HandleEvaluate(func(call *pkltest.Call) ([]byte, error) {
+		return []byte{0x01, 0x02}, nil
+	})
+	manager := newServerManager(t, server)
+
+	cache, err := pkl.NewEvaluationCache(pkl.EvaluationCacheOptions{})
+	if !assert.NoError(t, err) {
+		return
+	}
+	recorder := &fakeMetricsRecorder{}
+	ev, err := manager.NewEvaluator(context.Background(), pkl.WithEvaluationCache(cache), pkl.WithMetricsRecorder(recorder))
+	if !assert.NoError(t, err) {
+		return
+	}
+	for range 2 {
+		_, err = ev.EvaluateExpressionRaw(context.Background(), pkl.TextSource("foo = 1"), "foo")
+		assert.NoError(t, err)
+	}
+
+	stats, ok := pkl.EvaluatorStats(ev)
+	assert.True(t, ok)
+	assert.Equal(t, int64(2), stats.Evaluations)
+	assert.Equal(t, int64(1), stats.CacheHits)
+	assert.Equal(t, int64(2), stats.EvaluationLatency.Count)
+	assert.Equal(t, int64(2), stats.BytesReceived)
+
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	if assert.Len(t, recorder.evaluations, 2) {
+		assert.False(t, recorder.evaluations[0].CacheHit)
+		assert.True(t, recorder.evaluations[1].CacheHit)
+		assert.Equal(t, 2, recorder.evaluations[1].Bytes)
+	}
+}
@@ -0,0 +1,138 @@
+//===----------------------------------------------------------------------===//
+// Copyright © 2026 Apple Inc. and the Pkl project authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//===----------------------------------------------------------------------===//
+
+package pkltest
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Codes of Pkl's binary encoding.
+const (
+	codeMap   = 0x02
+	codeList  = 0x04
+	codeBytes = 0x0F
+)
+
+// Encode encodes v in Pkl's binary encoding, for use as the result of an evaluation.
+//
+// Supported values are nil, booleans, numbers, strings, byte slices (as Pkl Bytes), slices and
+// arrays (as Pkl Lists), and maps (as Pkl Maps), nested arbitrarily.
+func Encode(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	if err := encodeValue(enc, reflect.ValueOf(v)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// MustEncode is like Encode, but panics if v cannot be encoded.
+func MustEncode(v any) []byte {
+	b, err := Encode(v)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func encodeValue(enc *msgpack.Encoder, v reflect.Value) error {
+	if !v.IsValid() {
+		return enc.EncodeNil()
+	}
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return enc.EncodeNil()
+		}
+		return encodeValue(enc, v.Elem())
+	case reflect.Bool:
+		return enc.EncodeBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return enc.EncodeInt(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return enc.EncodeUint(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return enc.EncodeFloat64(v.Float())
+	case reflect.String:
+		return enc.EncodeString(v.String())
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			if err := enc.EncodeArrayLen(2); err != nil {
+				return err
+			}
+			if err := enc.EncodeInt(codeBytes); err != nil {
+				return err
+			}
+			return enc.EncodeBytes(bytesOf(v))
+		}
+		if err := enc.EncodeArrayLen(2); err != nil {
+			return err
+		}
+		if err := enc.EncodeInt(codeList); err != nil {
+			return err
+		}
+		if err := enc.EncodeArrayLen(v.Len()); err != nil {
+			return err
+		}
+		for i := range v.Len() {
+			if err := encodeValue(enc, v.Index(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Map:
+		if err := enc.EncodeArrayLen(2); err != nil {
+			return err
+		}
+		if err := enc.EncodeInt(codeMap); err != nil {
+			return err
+		}
+		if err := enc.EncodeMapLen(v.Len()); err != nil {
+			return err
+		}
+		keys := v.MapKeys()
+		// sort keys, so that the encoding is deterministic.
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+		for _, key := range keys {
+			if err := encodeValue(enc, key); err != nil {
+				return err
+			}
+			if err := encodeValue(enc, v.MapIndex(key)); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("pkltest: cannot encode value of type %s", v.Type())
+	}
+}
+
+func bytesOf(v reflect.Value) []byte {
+	if v.Kind() == reflect.Slice {
+		return v.Bytes()
+	}
+	b := make([]byte, v.Len())
+	reflect.Copy(reflect.ValueOf(b), v)
+	return b
+}
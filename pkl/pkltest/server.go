@@ -0,0 +1,711 @@
+//===----------------------------------------------------------------------===//
+// Copyright © 2026 Apple Inc. and the Pkl project authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//===----------------------------------------------------------------------===//
+
+// Package pkltest provides a fake Pkl server, for testing code that uses pkl-go without installing
+// Pkl.
+//
+// The fake speaks Pkl's message passing API over in-memory pipes, or over the connections accepted
+// by a listener. Its responses to evaluations are scripted by the test, which can also call back
+// into the client's readers and logger, and simulate crashes and malformed messages.
+//
+//	server := pkltest.NewServer()
+//	defer server.Close()
+//	server.HandleEvaluate(func(call *pkltest.Call) ([]byte, error) {
+//		return pkltest.Encode("hello")
+//	})
+//	manager := pkl.NewEvaluatorManagerWithTransport(server.Transport())
+package pkltest
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+
+	"github.com/apple/pkl-go/pkl"
+	"github.com/apple/pkl-go/pkl/internal/msgapi"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// ServerOptions is the set of options available to control a Server.
+type ServerOptions struct {
+	// PklVersion is the version of Pkl that the server claims to run.
+	//
+	// Defaults to 0.32.0.
+	PklVersion string
+
+	// StrayMessages mixes in messages that do not belong to any evaluator or request, like
+	// responses to requests that were already answered, and messages that pkl-go does not know.
+	StrayMessages bool
+}
+
+// WithPklVersion sets the version of Pkl that the server claims to run.
+var WithPklVersion = func(version string) func(opts *ServerOptions) {
+	return func(opts *ServerOptions) {
+		opts.PklVersion = version
+	}
+}
+
+// WithStrayMessages mixes in messages that do not belong to any evaluator or request.
+var WithStrayMessages = func(opts *ServerOptions) {
+	opts.StrayMessages = true
+}
+
+// CreateEvaluatorRequest is a request from the client to create an evaluator.
+type CreateEvaluatorRequest struct {
+	// EvaluatorId is the ID that the server assigns to the evaluator.
+	EvaluatorId int64
+
+	AllowedModules        []string
+	AllowedResources      []string
+	ModuleReaderSchemes   []string
+	ResourceReaderSchemes []string
+	Env                   map[string]string
+	Properties            map[string]string
+	OutputFormat          string
+	TimeoutSeconds        int64
+	RootDir               string
+	CacheDir              string
+}
+
+// CreateEvaluatorHandler decides whether an evaluator can be created.
+//
+// A non-nil error is sent to the client as the reason why the evaluator cannot be created.
+type CreateEvaluatorHandler func(req CreateEvaluatorRequest) error
+
+// EvaluateHandler scripts the response to an evaluation.
+//
+// The result must be encoded in Pkl's binary encoding, for example with Encode.
+// A non-nil error is sent to the client as the error output of Pkl.
+type EvaluateHandler func(call *Call) ([]byte, error)
+
+// Server is a fake Pkl server.
+//
+// Each call to the Connect method of its Transport starts a new session, like spawning a new
+// `pkl server` process would. So does each connection accepted by Serve, and each call to the Init
+// method of its MessageTransport.
+type Server struct {
+	options ServerOptions
+
+	nextEvaluatorId atomic.Int64
+	nextRequestId   atomic.Int64
+
+	// mu guards the fields below.
+	mu               sync.Mutex
+	closed           bool
+	sessions         map[*session]struct{}
+	evaluators       map[int64]CreateEvaluatorRequest
+	onCreate         CreateEvaluatorHandler
+	onEvaluate       EvaluateHandler
+	onClose          func(evaluatorId int64)
+	sessionsStarted  int
+	evaluationsCount int
+}
+
+// NewServer creates a new fake Pkl server.
+func NewServer(opts ...func(options *ServerOptions)) *Server {
+	o := ServerOptions{PklVersion: "0.32.0"}
+	for _, f := range opts {
+		f(&o)
+	}
+	return &Server{
+		options:    o,
+		sessions:   make(map[*session]struct{}),
+		evaluators: make(map[int64]CreateEvaluatorRequest),
+	}
+}
+
+// HandleCreateEvaluator sets the handler that decides whether evaluators can be created.
+//
+// By default, every evaluator is created.
+func (s *Server) HandleCreateEvaluator(handler CreateEvaluatorHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onCreate = handler
+}
+
+// HandleEvaluate sets the handler that scripts the response to evaluations.
+//
+// Evaluations are handled concurrently. By default, every evaluation fails.
+// Like in Pkl, evaluations also fail if their evaluator was not created within the same session.
+func (s *Server) HandleEvaluate(handler EvaluateHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onEvaluate = handler
+}
+
+// HandleCloseEvaluator sets a function that is called whenever the client closes an evaluator.
+func (s *Server) HandleCloseEvaluator(handler func(evaluatorId int64)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onClose = handler
+}
+
+// Transport returns a pkl.Transport that connects to the server.
+func (s *Server) Transport() pkl.Transport {
+	return &transport{server: s}
+}
+
+// MessageTransport returns a pkl.MessageTransport that exchanges messages with the server.
+//
+// Its Init method starts a new session.
+func (s *Server) MessageTransport() pkl.MessageTransport {
+	return &messageTransport{
+		transport: transport{server: s},
+		in:        make(chan pkl.Message),
+		out:       make(chan pkl.Message),
+		closed:    make(chan error),
+		closing:   make(chan struct{}),
+	}
+}
+
+// Serve serves every connection accepted by listener as a new session, like a `pkl server` that
+// is reached over a socket, until listener is closed.
+func (s *Server) Serve(listener net.Listener) error {
+	for {
+		conn, err := listener.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err = s.startSession(conn); err != nil {
+			_ = conn.Close()
+		}
+	}
+}
+
+// startSession serves conn as a new session.
+func (s *Server) startSession(conn net.Conn) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return errors.New("pkltest: server is closed")
+	}
+	sess := &session{
+		server:     s,
+		conn:       conn,
+		pending:    make(map[int64]chan msgapi.OutgoingMessage),
+		done:       make(chan struct{}),
+		evaluators: make(map[int64]chan struct{}),
+	}
+	s.sessions[sess] = struct{}{}
+	s.sessionsStarted++
+	go sess.serve()
+	return nil
+}
+
+// Evaluators returns the evaluators that are currently open, across all sessions.
+func (s *Server) Evaluators() []CreateEvaluatorRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	evaluators := make([]CreateEvaluatorRequest, 0, len(s.evaluators))
+	for _, req := range s.evaluators {
+		evaluators = append(evaluators, req)
+	}
+	return evaluators
+}
+
+// Sessions returns the number of sessions that have been started.
+func (s *Server) Sessions() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sessionsStarted
+}
+
+// Evaluations returns the number of evaluations that have been received.
+func (s *Server) Evaluations() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.evaluationsCount
+}
+
+// Crash abruptly ends every session, like a crash of the Pkl process would.
+func (s *Server) Crash() {
+	for _, sess := range s.liveSessions() {
+		sess.close()
+	}
+}
+
+// SendMalformedMessage sends bytes that are not a valid message on every session.
+func (s *Server) SendMalformedMessage() {
+	for _, sess := range s.liveSessions() {
+		// 0xc1 is never used by msgpack.
+		_ = sess.write([]byte{0xc1})
+	}
+}
+
+// SendUnknownMessage sends a message with a code that pkl-go does not know on every session, like
+// a newer version of Pkl could.
+func (s *Server) SendUnknownMessage(code int) {
+	for _, sess := range s.liveSessions() {
+		_ = sess.send(&msgapi.Unknown{Code: code})
+	}
+}
+
+// Close ends every session, and refuses new ones.
+func (s *Server) Close() {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+	s.Crash()
+}
+
+func (s *Server) liveSessions() []*session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sessions := make([]*session, 0, len(s.sessions))
+	for sess := range s.sessions {
+		sessions = append(sessions, sess)
+	}
+	return sessions
+}
+
+type transport struct {
+	server *Server
+}
+
+var _ pkl.Transport = (*transport)(nil)
+
+func (t *transport) Connect() (io.ReadWriteCloser, error) {
+	client, conn := net.Pipe()
+	if err := t.server.startSession(conn); err != nil {
+		_ = client.Close()
+		_ = conn.Close()
+		return nil, err
+	}
+	return client, nil
+}
+
+func (t *transport) PklVersion() (string, error) {
+	return t.server.options.PklVersion, nil
+}
+
+// messageTransport relays the messages of a session, framed like `pkl server` frames them.
+type messageTransport struct {
+	transport
+	conn    io.ReadWriteCloser
+	in      chan pkl.Message
+	out     chan pkl.Message
+	closed  chan error
+	closing chan struct{}
+	relays  sync.WaitGroup
+}
+
+var _ pkl.MessageTransport = (*messageTransport)(nil)
+
+func (t *messageTransport) Init() error {
+	conn, err := t.Connect()
+	if err != nil {
+		return err
+	}
+	t.conn = conn
+	t.relays.Add(2)
+	go t.read()
+	go t.write()
+	return nil
+}
+
+func (t *messageTransport) read() {
+	defer t.relays.Done()
+	dec := msgpack.NewDecoder(t.conn)
+	for {
+		raw, err := dec.DecodeRaw()
+		if err != nil {
+			t.report(fmt.Errorf("pkltest: session ended: %w", err))
+			return
+		}
+		select {
+		case t.in <- pkl.Message(raw):
+		case <-t.closing:
+			return
+		}
+	}
+}
+
+func (t *messageTransport) write() {
+	defer t.relays.Done()
+	for {
+		select {
+		case msg := <-t.out:
+			if _, err := t.conn.Write(msg); err != nil {
+				t.report(fmt.Errorf("pkltest: session ended: %w", err))
+				return
+			}
+		case <-t.closing:
+			return
+		}
+	}
+}
+
+func (t *messageTransport) report(err error) {
+	select {
+	case t.closed <- err:
+	case <-t.closing:
+	}
+}
+
+func (t *messageTransport) Deinit() error {
+	close(t.closing)
+	if t.conn != nil {
+		_ = t.conn.Close()
+	}
+	t.relays.Wait()
+	close(t.in)
+	close(t.out)
+	close(t.closed)
+	return nil
+}
+
+func (t *messageTransport) InChan() chan pkl.Message {
+	return t.in
+}
+
+func (t *messageTransport) OutChan() chan pkl.Message {
+	return t.out
+}
+
+func (t *messageTransport) ClosedChan() chan error {
+	return t.closed
+}
+
+func (t *messageTransport) GetVersion() (string, error) {
+	return t.PklVersion()
+}
+
+// session is the server side of a connection.
+type session struct {
+	server *Server
+	conn   net.Conn
+	done   chan struct{}
+
+	closeOnce sync.Once
+	writeMu   sync.Mutex
+
+	// mu guards pending and evaluators.
+	mu sync.Mutex
+	// pending are the callbacks into the client that await a response, keyed by request ID.
+	pending map[int64]chan msgapi.OutgoingMessage
+	// evaluators are the evaluators created within this session, keyed by ID. Their channel is
+	// closed once the evaluator is closed, or the session ends.
+	evaluators map[int64]chan struct{}
+}
+
+func (sess *session) close() {
+	sess.closeOnce.Do(func() {
+		_ = sess.conn.Close()
+		close(sess.done)
+		s := sess.server
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.sessions, sess)
+		sess.mu.Lock()
+		defer sess.mu.Unlock()
+		for id, done := range sess.evaluators {
+			delete(s.evaluators, id)
+			close(done)
+		}
+		clear(sess.evaluators)
+	})
+}
+
+func (sess *session) write(b []byte) error {
+	sess.writeMu.Lock()
+	defer sess.writeMu.Unlock()
+	_, err := sess.conn.Write(b)
+	return err
+}
+
+func (sess *session) send(msg msgapi.IncomingMessage) error {
+	b, err := msgapi.Encode(msg)
+	if err != nil {
+		return err
+	}
+	return sess.write(b)
+}
+
+func (sess *session) serve() {
+	defer sess.close()
+	dec := msgpack.NewDecoder(sess.conn)
+	for {
+		msg, err := msgapi.DecodeOutgoing(dec)
+		if err != nil {
+			return
+		}
+		switch msg := msg.(type) {
+		case *msgapi.CreateEvaluator:
+			// the handler may block, for example to delay the response.
+			go sess.createEvaluator(msg)
+		case *msgapi.CloseEvaluator:
+			sess.closeEvaluator(msg.EvaluatorId)
+		case *msgapi.Evaluate:
+			go sess.evaluate(msg)
+		case *msgapi.ReadResourceResponse:
+			sess.respond(msg.RequestId, msg)
+		case *msgapi.ReadModuleResponse:
+			sess.respond(msg.RequestId, msg)
+		case *msgapi.ListResourcesResponse:
+			sess.respond(msg.RequestId, msg)
+		case *msgapi.ListModulesResponse:
+			sess.respond(msg.RequestId, msg)
+		}
+	}
+}
+
+func (sess *session) createEvaluator(msg *msgapi.CreateEvaluator) {
+	s := sess.server
+	req := CreateEvaluatorRequest{
+		AllowedModules:   msg.AllowedModules,
+		AllowedResources: msg.AllowedResources,
+		Env:              msg.Env,
+		Properties:       msg.Properties,
+		OutputFormat:     msg.OutputFormat,
+		TimeoutSeconds:   msg.TimeoutSeconds,
+		RootDir:          msg.RootDir,
+		CacheDir:         msg.CacheDir,
+	}
+	for _, reader := range msg.ModuleReaders {
+		req.ModuleReaderSchemes = append(req.ModuleReaderSchemes, reader.Scheme)
+	}
+	for _, reader := range msg.ResourceReaders {
+		req.ResourceReaderSchemes = append(req.ResourceReaderSchemes, reader.Scheme)
+	}
+	s.mu.Lock()
+	onCreate := s.onCreate
+	s.mu.Unlock()
+	resp := &msgapi.CreateEvaluatorResponse{RequestId: msg.RequestId}
+	if onCreate != nil {
+		if err := onCreate(req); err != nil {
+			resp.Error = err.Error()
+			_ = sess.send(resp)
+			return
+		}
+	}
+	req.EvaluatorId = s.nextEvaluatorId.Add(1)
+	s.mu.Lock()
+	sess.mu.Lock()
+	select {
+	case <-sess.done:
+		// the session ended while the handler ran.
+		sess.mu.Unlock()
+		s.mu.Unlock()
+		return
+	default:
+	}
+	s.evaluators[req.EvaluatorId] = req
+	sess.evaluators[req.EvaluatorId] = make(chan struct{})
+	sess.mu.Unlock()
+	s.mu.Unlock()
+	resp.EvaluatorId = req.EvaluatorId
+	_ = sess.send(resp)
+	if s.options.StrayMessages {
+		_ = sess.send(&msgapi.CreateEvaluatorResponse{RequestId: -msg.RequestId, EvaluatorId: s.nextEvaluatorId.Add(1)})
+	}
+}
+
+func (sess *session) closeEvaluator(evaluatorId int64) {
+	s := sess.server
+	s.mu.Lock()
+	delete(s.evaluators, evaluatorId)
+	onClose := s.onClose
+	s.mu.Unlock()
+	if onClose != nil {
+		onClose(evaluatorId)
+	}
+	sess.mu.Lock()
+	if done, ok := sess.evaluators[evaluatorId]; ok {
+		delete(sess.evaluators, evaluatorId)
+		close(done)
+	}
+	sess.mu.Unlock()
+}
+
+func (sess *session) evaluate(msg *msgapi.Evaluate) {
+	s := sess.server
+	s.mu.Lock()
+	s.evaluationsCount++
+	onEvaluate := s.onEvaluate
+	s.mu.Unlock()
+	call := &Call{
+		EvaluatorId: msg.EvaluatorId,
+		ModuleUri:   msg.ModuleUri,
+		ModuleText:  msg.ModuleText,
+		Expr:        msg.Expr,
+		session:     sess,
+	}
+	resp := &msgapi.EvaluateResponse{RequestId: msg.RequestId, EvaluatorId: msg.EvaluatorId}
+	sess.mu.Lock()
+	done, exists := sess.evaluators[msg.EvaluatorId]
+	sess.mu.Unlock()
+	call.done = done
+	if !exists {
+		// like Pkl, which only knows the evaluators created within the same process.
+		resp.Error = fmt.Sprintf("pkltest: evaluator %d does not exist", msg.EvaluatorId)
+	} else if onEvaluate == nil {
+		resp.Error = "pkltest: no evaluate handler"
+	} else if result, err := onEvaluate(call); err != nil {
+		resp.Error = err.Error()
+	} else {
+		resp.Result = result
+	}
+	_ = sess.send(resp)
+	if s.options.StrayMessages {
+		sess.sendStrayMessages(msg)
+	}
+}
+
+// sendStrayMessages sends messages that look like they belong to msg, but do not.
+func (sess *session) sendStrayMessages(msg *msgapi.Evaluate) {
+	_ = sess.send(&msgapi.EvaluateResponse{RequestId: msg.RequestId, EvaluatorId: msg.EvaluatorId, Result: []byte{0xc0}})
+	_ = sess.send(&msgapi.EvaluateResponse{RequestId: msg.RequestId + 1, EvaluatorId: msg.EvaluatorId})
+	_ = sess.send(&msgapi.EvaluateResponse{RequestId: msg.RequestId, EvaluatorId: -1})
+	_ = sess.send(&msgapi.Log{EvaluatorId: -1, Level: 1, Message: "stray"})
+	_ = sess.send(&msgapi.ReadResource{RequestId: -msg.RequestId, EvaluatorId: -1, Uri: "env:HOME"})
+	_ = sess.send(&msgapi.Unknown{Code: 0x7f})
+}
+
+func (sess *session) respond(requestId int64, msg msgapi.OutgoingMessage) {
+	sess.mu.Lock()
+	ch, ok := sess.pending[requestId]
+	delete(sess.pending, requestId)
+	sess.mu.Unlock()
+	if ok {
+		ch <- msg
+	}
+}
+
+// request sends a callback into the client, and waits for its response.
+func (sess *session) request(newMsg func(requestId int64) msgapi.IncomingMessage) (msgapi.OutgoingMessage, error) {
+	requestId := sess.server.nextRequestId.Add(1)
+	ch := make(chan msgapi.OutgoingMessage, 1)
+	sess.mu.Lock()
+	sess.pending[requestId] = ch
+	sess.mu.Unlock()
+	if err := sess.send(newMsg(requestId)); err != nil {
+		return nil, err
+	}
+	select {
+	case resp := <-ch:
+		return resp, nil
+	case <-sess.done:
+		return nil, errors.New("pkltest: session closed")
+	}
+}
+
+// Call is an evaluation received by the server.
+//
+// While handling a call, the server can call back into the client's readers and logger.
+type Call struct {
+	EvaluatorId int64
+	ModuleUri   string
+	ModuleText  string
+	Expr        string
+
+	session *session
+	done    <-chan struct{}
+}
+
+// Done returns a channel that is closed once the evaluator of the call is closed, or its session
+// ends, for example because the client canceled the evaluation.
+//
+// Handlers that never complete an evaluation should return once it is closed.
+func (c *Call) Done() <-chan struct{} {
+	return c.done
+}
+
+// Log sends a log message to the client.
+func (c *Call) Log(level pkl.LogLevel, message, frameUri string) error {
+	return c.session.send(&msgapi.Log{EvaluatorId: c.EvaluatorId, Level: int(level), Message: message, FrameUri: frameUri})
+}
+
+// ReadResource asks the client to read a resource.
+//
+// If the client reports that the resource does not exist, pkl.ResourceNotFound is returned.
+func (c *Call) ReadResource(uri string) ([]byte, error) {
+	resp, err := c.session.request(func(requestId int64) msgapi.IncomingMessage {
+		return &msgapi.ReadResource{RequestId: requestId, EvaluatorId: c.EvaluatorId, Uri: uri}
+	})
+	if err != nil {
+		return nil, err
+	}
+	msg := resp.(*msgapi.ReadResourceResponse)
+	switch {
+	case msg.Error != "":
+		return nil, errors.New(msg.Error)
+	case msg.Contents == nil:
+		return nil, pkl.ResourceNotFound
+	default:
+		return *msg.Contents, nil
+	}
+}
+
+// ReadModule asks the client to read a module.
+func (c *Call) ReadModule(uri string) (string, error) {
+	resp, err := c.session.request(func(requestId int64) msgapi.IncomingMessage {
+		return &msgapi.ReadModule{RequestId: requestId, EvaluatorId: c.EvaluatorId, Uri: uri}
+	})
+	if err != nil {
+		return "", err
+	}
+	msg := resp.(*msgapi.ReadModuleResponse)
+	if msg.Error != "" {
+		return "", errors.New(msg.Error)
+	}
+	return msg.Contents, nil
+}
+
+// ListResources asks the client to list the resources at a base URI.
+func (c *Call) ListResources(uri string) ([]pkl.PathElement, error) {
+	resp, err := c.session.request(func(requestId int64) msgapi.IncomingMessage {
+		return &msgapi.ListResources{RequestId: requestId, EvaluatorId: c.EvaluatorId, Uri: uri}
+	})
+	if err != nil {
+		return nil, err
+	}
+	msg := resp.(*msgapi.ListResourcesResponse)
+	return pathElements(msg.PathElements, msg.Error)
+}
+
+// ListModules asks the client to list the modules at a base URI.
+func (c *Call) ListModules(uri string) ([]pkl.PathElement, error) {
+	resp, err := c.session.request(func(requestId int64) msgapi.IncomingMessage {
+		return &msgapi.ListModules{RequestId: requestId, EvaluatorId: c.EvaluatorId, Uri: uri}
+	})
+	if err != nil {
+		return nil, err
+	}
+	msg := resp.(*msgapi.ListModulesResponse)
+	return pathElements(msg.PathElements, msg.Error)
+}
+
+func pathElements(elements []*msgapi.PathElement, errorOutput string) ([]pkl.PathElement, error) {
+	if errorOutput != "" {
+		return nil, errors.New(errorOutput)
+	}
+	ret := make([]pkl.PathElement, len(elements))
+	for i, elem := range elements {
+		ret[i] = pkl.NewPathElement(elem.Name, elem.IsDirectory)
+	}
+	return ret, nil
+}
+
+// Errorf formats an error in the shape of Pkl's error output.
+func Errorf(format string, args ...any) error {
+	return fmt.Errorf("–– Pkl Error ––\n"+format, args...)
+}
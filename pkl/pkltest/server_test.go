@@ -0,0 +1,277 @@
+//===----------------------------------------------------------------------===//
+// Copyright © 2026 Apple Inc. and the Pkl project authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//===----------------------------------------------------------------------===//
+
+package pkltest
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/apple/pkl-go/pkl"
+	"github.com/stretchr/testify/assert"
+)
+
+type secretReader struct{}
+
+func (r secretReader) Scheme() string {
+	return "secret"
+}
+
+func (r secretReader) IsGlobbable() bool {
+	return false
+}
+
+func (r secretReader) HasHierarchicalUris() bool {
+	return false
+}
+
+func (r secretReader) ListElements(url.URL) ([]pkl.PathElement, error) {
+	return []pkl.PathElement{pkl.NewPathElement("password", false)}, nil
+}
+
+func (r secretReader) Read(uri url.URL) ([]byte, error) {
+	if uri.Opaque == "missing" {
+		return nil, pkl.ResourceNotFound
+	}
+	return []byte("hunter2"), nil
+}
+
+var _ pkl.ResourceReader = secretReader{}
+
+func TestServer(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	server.HandleEvaluate(func(call *Call) ([]byte, error) {
+		switch call.Expr {
+		case "secret":
+			contents, err := call.ReadResource("secret:password")
+			if err != nil {
+				return nil, err
+			}
+			_, err = call.ReadResource("secret:missing")
+			if err != pkl.ResourceNotFound {
+				return nil, Errorf("expected ResourceNotFound, got %v", err)
+			}
+			elements, err := call.ListResources("secret:")
+			if err != nil {
+				return nil, err
+			}
+			return Encode(map[string]any{elements[0].Name(): string(contents)})
+		case "trace":
+			if err := call.Log(pkl.LogLevelTrace, "hello", call.ModuleUri); err != nil {
+				return nil, err
+			}
+			return Encode([]any{1, "two"})
+		default:
+			return nil, Errorf("Cannot find property `%s`.", call.Expr)
+		}
+	})
+	manager := pkl.NewEvaluatorManagerWithTransport(server.Transport())
+	defer func() { assert.NoError(t, manager.Close()) }()
+
+	logged := make(chan pkl.LogMessage, 1)
+	ev, err := manager.NewEvaluator(context.Background(),
+		pkl.WithResourceReader(secretReader{}),
+		pkl.WithInterceptor(pkl.Interceptor{
+			Log: func(msg pkl.LogMessage, next pkl.LogHandler) { logged <- msg },
+		}),
+	)
+	if !assert.NoError(t, err) {
+		return
+	}
+	if assert.Len(t, server.Evaluators(), 1) {
+		assert.Equal(t, []string{"secret"}, server.Evaluators()[0].ResourceReaderSchemes)
+	}
+
+	var secrets map[string]string
+	assert.NoError(t, ev.EvaluateExpression(context.Background(), pkl.TextSource(""), "secret", &secrets))
+	assert.Equal(t, map[string]string{"password": "hunter2"}, secrets)
+
+	var list []any
+	assert.NoError(t, ev.EvaluateExpression(context.Background(), pkl.TextSource(""), "trace", &list))
+	assert.Equal(t, []any{1, "two"}, list)
+	assert.Equal(t, pkl.LogMessage{Level: pkl.LogLevelTrace, Message: "hello", FrameUri: "repl:text"}, <-logged)
+
+	_, err = ev.EvaluateExpressionRaw(context.Background(), pkl.TextSource(""), "missing")
+	assert.ErrorIs(t, err, &pkl.EvalError{})
+	assert.Equal(t, 3, server.Evaluations())
+
+	assert.NoError(t, ev.Close())
+	assert.Eventually(t, func() bool { return len(server.Evaluators()) == 0 }, time.Second, 10*time.Millisecond)
+}
+
+func TestServer_HandleCreateEvaluator(t *testing.T) {
+	server := NewServer(WithPklVersion("0.29.0"))
+	defer server.Close()
+	server.HandleCreateEvaluator(func(req CreateEvaluatorRequest) error {
+		return Errorf("Invalid evaluator settings.")
+	})
+	manager := pkl.NewEvaluatorManagerWithTransport(server.Transport())
+	defer func() { assert.NoError(t, manager.Close()) }()
+
+	version, err := manager.GetVersion()
+	assert.NoError(t, err)
+	assert.Equal(t, "0.29.0", version)
+	_, err = manager.NewEvaluator(context.Background())
+	assert.ErrorContains(t, err, "Invalid evaluator settings.")
+}
+
+func TestServer_HandleCloseEvaluator(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	server.HandleEvaluate(func(call *Call) ([]byte, error) {
+		<-call.Done()
+		return nil, errors.New("evaluator closed")
+	})
+	closed := make(chan int64, 1)
+	server.HandleCloseEvaluator(func(evaluatorId int64) { closed <- evaluatorId })
+	manager := pkl.NewEvaluatorManagerWithTransport(server.Transport())
+	defer func() { assert.NoError(t, manager.Close()) }()
+
+	ev, err := manager.NewEvaluator(context.Background())
+	if !assert.NoError(t, err) {
+		return
+	}
+	result := make(chan error, 1)
+	go func() {
+		_, err := ev.EvaluateExpressionRaw(context.Background(), pkl.TextSource(""), "foo")
+		result <- err
+	}()
+	assert.Eventually(t, func() bool { return server.Evaluations() == 1 }, time.Second, time.Millisecond)
+	assert.NoError(t, ev.Close())
+	assert.ErrorIs(t, <-result, pkl.ErrEvaluatorClosed)
+	assert.Equal(t, int64(1), <-closed)
+	assert.Empty(t, server.Evaluators())
+}
+
+func TestServer_Serve(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		return
+	}
+	server := NewServer()
+	defer server.Close()
+	server.HandleEvaluate(func(call *Call) ([]byte, error) {
+		return Encode(call.Expr)
+	})
+	served := make(chan error, 1)
+	go func() { served <- server.Serve(listener) }()
+
+	manager := pkl.NewEvaluatorManagerWithOptions(
+		pkl.WithSocket(pkl.SocketOptions{Network: "tcp", Address: listener.Addr().String(), PklVersion: "0.30.0"}),
+	)
+	defer func() { assert.NoError(t, manager.Close()) }()
+	ev, err := manager.NewEvaluator(context.Background())
+	if !assert.NoError(t, err) {
+		return
+	}
+	out, err := ev.EvaluateExpressionRaw(context.Background(), pkl.TextSource(""), "foo")
+	assert.NoError(t, err)
+	assert.Equal(t, MustEncode("foo"), out)
+	assert.Equal(t, 1, server.Sessions())
+
+	assert.NoError(t, listener.Close())
+	assert.NoError(t, <-served)
+}
+
+func TestServer_Crash(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	server.HandleEvaluate(func(call *Call) ([]byte, error) {
+		return Encode("ok")
+	})
+	restarted := make(chan pkl.RestartEvent, 1)
+	manager := pkl.NewEvaluatorManagerWithTransport(server.Transport(), pkl.WithSupervisor(pkl.SupervisorOptions{
+		OnRestart: func(event pkl.RestartEvent) { restarted <- event },
+	}))
+	defer func() { assert.NoError(t, manager.Close()) }()
+
+	ev, err := manager.NewEvaluator(context.Background())
+	if !assert.NoError(t, err) {
+		return
+	}
+	server.Crash()
+	assert.NoError(t, (<-restarted).Err)
+	assert.Equal(t, 2, server.Sessions())
+	out, err := ev.EvaluateOutputText(context.Background(), pkl.TextSource(""))
+	assert.NoError(t, err)
+	assert.Equal(t, "ok", out)
+}
+
+func TestServer_SendMalformedMessage(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	manager := pkl.NewEvaluatorManagerWithTransport(server.Transport())
+	defer func() { assert.NoError(t, manager.Close()) }()
+
+	ev, err := manager.NewEvaluator(context.Background())
+	if !assert.NoError(t, err) {
+		return
+	}
+	server.SendMalformedMessage()
+	assert.Eventually(t, ev.Closed, time.Second, 10*time.Millisecond)
+}
+
+func TestServer_SendUnknownMessage(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	server.HandleEvaluate(func(call *Call) ([]byte, error) {
+		return Encode("ok")
+	})
+	manager := pkl.NewEvaluatorManagerWithTransport(server.Transport())
+	defer func() { assert.NoError(t, manager.Close()) }()
+
+	ev, err := manager.NewEvaluator(context.Background())
+	if !assert.NoError(t, err) {
+		return
+	}
+	server.SendUnknownMessage(0x7f)
+	out, err := ev.EvaluateOutputText(context.Background(), pkl.TextSource(""))
+	assert.NoError(t, err)
+	assert.Equal(t, "ok", out)
+	assert.False(t, ev.Closed())
+}
+
+func TestServer_MessageTransport(t *testing.T) {
+	server := NewServer(WithPklVersion("0.30.0"))
+	defer server.Close()
+	server.HandleEvaluate(func(call *Call) ([]byte, error) {
+		return Encode("ok")
+	})
+	manager := pkl.NewEvaluatorManagerWithMessageTransport(server.MessageTransport())
+	defer func() { assert.NoError(t, manager.Close()) }()
+
+	version, err := manager.GetVersion()
+	assert.NoError(t, err)
+	assert.Equal(t, "0.30.0", version)
+	ev, err := manager.NewEvaluator(context.Background())
+	if !assert.NoError(t, err) {
+		return
+	}
+	out, err := ev.EvaluateOutputText(context.Background(), pkl.TextSource(""))
+	assert.NoError(t, err)
+	assert.Equal(t, "ok", out)
+
+	// the manager is closed with the error reported by the transport.
+	server.Crash()
+	assert.Eventually(t, ev.Closed, time.Second, 10*time.Millisecond)
+	_, err = manager.NewEvaluator(context.Background())
+	assert.ErrorIs(t, err, pkl.ErrManagerClosed)
+}
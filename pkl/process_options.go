@@ -0,0 +1,167 @@
+//===----------------------------------------------------------------------===//
+// Copyright © 2026 Apple Inc. and the Pkl project authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//===----------------------------------------------------------------------===//
+
+package pkl
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+)
+
+// defaultShutdownGracePeriod is the default of ProcessOptions.ShutdownGracePeriod.
+const defaultShutdownGracePeriod = 5 * time.Second
+
+// ProcessOptions configures the Pkl child process spawned by an EvaluatorManager.
+type ProcessOptions struct {
+	// Env sets environment variables of the Pkl process.
+	//
+	// By default, these are merged into the environment of the current process, taking precedence
+	// over variables of the same name.
+	Env map[string]string
+
+	// ReplaceEnv, if true, starts the Pkl process with only the variables in Env, instead of
+	// merging them into the environment of the current process.
+	ReplaceEnv bool
+
+	// EnvAllowlist, if not empty, limits the variables inherited from the environment of the
+	// current process to the ones named.
+	//
+	// Variables in Env are always set.
+	EnvAllowlist []string
+
+	// Stderr receives the standard error of the Pkl process.
+	//
+	// If nil, defaults to os.Stderr.
+	Stderr io.Writer
+
+	// WorkingDir is the working directory of the Pkl process.
+	//
+	// If empty, the Pkl process runs in the working directory of the current process.
+	WorkingDir string
+
+	// ExtraArgs are passed to the Pkl CLI before the `server` subcommand, as in
+	// `pkl <ExtraArgs> server`.
+	//
+	// These are arguments of the Pkl CLI, not of the JVM that runs it. To tune the JVM of the Java
+	// distribution of Pkl, for example its heap size, set `JAVA_OPTS` in Env instead.
+	ExtraArgs []string
+
+	// ShutdownGracePeriod is how long the Pkl process is given to exit after the manager is
+	// closed, before it is killed.
+	//
+	// Defaults to 5 seconds.
+	ShutdownGracePeriod time.Duration
+
+	// KillOnParentDeath, if true, kills the Pkl process when the current process dies, even if it
+	// crashes without closing the manager.
+	//
+	// Only supported on Linux; ignored on other platforms.
+	KillOnParentDeath bool
+}
+
+// WithProcessOptions configures the Pkl child process.
+var WithProcessOptions = func(process ProcessOptions) func(opts *EvaluatorManagerOptions) {
+	return func(opts *EvaluatorManagerOptions) {
+		opts.Process = process
+	}
+}
+
+// environ returns the environment of the Pkl process, in the form used by exec.Cmd.
+func (p *ProcessOptions) environ() []string {
+	var env []string
+	if !p.ReplaceEnv {
+		for _, kv := range os.Environ() {
+			name, _, _ := strings.Cut(kv, "=")
+			if len(p.EnvAllowlist) == 0 || slices.Contains(p.EnvAllowlist, name) {
+				env = append(env, kv)
+			}
+		}
+	}
+	names := make([]string, 0, len(p.Env))
+	for name := range p.Env {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		// exec.Cmd uses the last value of duplicate variables.
+		env = append(env, name+"="+p.Env[name])
+	}
+	if env == nil {
+		// a nil environment would make exec.Cmd inherit the current one.
+		env = []string{}
+	}
+	return env
+}
+
+// getenv returns the value of the environment variable name in the Pkl process.
+func (p *ProcessOptions) getenv(name string) string {
+	var value string
+	for _, kv := range p.environ() {
+		k, v, _ := strings.Cut(kv, "=")
+		// environment variables are case-insensitive on Windows.
+		if k == name || runtime.GOOS == "windows" && strings.EqualFold(k, name) {
+			value = v
+		}
+	}
+	return value
+}
+
+// resolve returns path made absolute the way the Pkl process sees it; relative paths are resolved
+// against WorkingDir.
+func (p *ProcessOptions) resolve(path string) (string, error) {
+	if filepath.IsAbs(path) {
+		return path, nil
+	}
+	return filepath.Abs(filepath.Join(p.WorkingDir, path))
+}
+
+// lookPath is like exec.LookPath, but resolves file the way the Pkl process does: relative paths
+// against WorkingDir, and bare names in the PATH of the Pkl process.
+func (p *ProcessOptions) lookPath(file string) (string, error) {
+	if strings.ContainsAny(file, `/`+string(filepath.Separator)) {
+		path, err := p.resolve(file)
+		if err != nil {
+			return "", err
+		}
+		return exec.LookPath(path)
+	}
+	for _, dir := range filepath.SplitList(p.getenv("PATH")) {
+		if dir == "" {
+			dir = "."
+		}
+		dir, err := p.resolve(dir)
+		if err != nil {
+			continue
+		}
+		if path, err := exec.LookPath(filepath.Join(dir, file)); err == nil {
+			return path, nil
+		}
+	}
+	return "", &exec.Error{Name: file, Err: exec.ErrNotFound}
+}
+
+func (p *ProcessOptions) stderr() io.Writer {
+	if p.Stderr == nil {
+		return os.Stderr
+	}
+	return p.Stderr
+}
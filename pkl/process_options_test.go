@@ -0,0 +1,98 @@
+//===----------------------------------------------------------------------===//
+// Copyright © 2026 Apple Inc. and the Pkl project authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//===----------------------------------------------------------------------===//
+
+package pkl
+
+import (
+	"bytes"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/apple/pkl-go/pkl/internal/msgapi"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProcessOptions_environ(t *testing.T) {
+	t.Setenv("PKL_GO_TEST_INHERITED", "inherited")
+	t.Setenv("PKL_GO_TEST_OVERRIDDEN", "inherited")
+
+	merged := (&ProcessOptions{Env: map[string]string{"PKL_GO_TEST_OVERRIDDEN": "overridden"}}).environ()
+	assert.Contains(t, merged, "PKL_GO_TEST_INHERITED=inherited")
+	// exec.Cmd uses the last value of duplicate variables.
+	assert.Equal(t, "PKL_GO_TEST_OVERRIDDEN=overridden", merged[len(merged)-1])
+
+	allowed := (&ProcessOptions{EnvAllowlist: []string{"PKL_GO_TEST_INHERITED"}, Env: map[string]string{"FOO": "bar"}}).environ()
+	assert.Equal(t, []string{"PKL_GO_TEST_INHERITED=inherited", "FOO=bar"}, allowed)
+
+	replaced := (&ProcessOptions{ReplaceEnv: true, Env: map[string]string{"B": "2", "A": "1"}}).environ()
+	assert.Equal(t, []string{"A=1", "B=2"}, replaced)
+
+	assert.Equal(t, []string{}, (&ProcessOptions{ReplaceEnv: true}).environ())
+}
+
+func TestExecEvaluator_ProcessOptions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	var stderr bytes.Buffer
+	dir := t.TempDir()
+	e := &execEvaluator{
+		in:         make(chan msgapi.IncomingMessage),
+		out:        make(chan msgapi.OutgoingMessage),
+		closed:     make(chan error),
+		pklCommand: []string{"sh", "-c", `echo "$FOO $(pwd) $*" >&2`, "sh"},
+		process: ProcessOptions{
+			Env:               map[string]string{"FOO": "bar"},
+			ReplaceEnv:        true,
+			Stderr:            &stderr,
+			WorkingDir:        dir,
+			ExtraArgs:         []string{"--extra"},
+			KillOnParentDeath: true,
+		},
+	}
+	if !assert.NoError(t, e.init()) {
+		return
+	}
+	select {
+	case <-e.closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("process did not exit")
+	}
+	assert.Equal(t, "bar "+dir+" --extra server\n", stderr.String())
+	// the extra arguments are not appended into the configured command.
+	assert.Len(t, e.pklCommand, 4)
+}
+
+func TestExecEvaluator_getVersion_concurrent(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	e := &execEvaluator{pklCommand: []string{"sh", "-c", `echo "Pkl 0.29.0 (Linux, Native)"`, "sh"}}
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			version, err := e.getVersion()
+			if assert.NoError(t, err) {
+				assert.Equal(t, "0.29.0", version.String())
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -65,6 +65,9 @@ type ProjectPackage struct {
 }
 
 // ProjectEvaluatorSettings is the Go representation of pkl.EvaluatorSettings
+//
+// Color has no effect on evaluators, because Pkl does not color the messages that it sends to
+// its clients.
 type ProjectEvaluatorSettings struct {
 	ExternalProperties      map[string]string                                `pkl:"externalProperties"`
 	Env                     map[string]string                                `pkl:"env"`
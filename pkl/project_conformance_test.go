@@ -0,0 +1,113 @@
+//===----------------------------------------------------------------------===//
+// Copyright © 2026 Apple Inc. and the Pkl project authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//===----------------------------------------------------------------------===//
+
+package pkl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestNewProjectEvaluator_conformance checks that NewProjectEvaluator evaluates the sample
+// projects in test_fixtures/projects like `pkl eval --project-dir`.
+//
+// There is a sample project for every setting of `evaluatorSettings` that changes the outcome of an
+// evaluation. Projects that fail to evaluate with the CLI, like the one that exceeds its timeout,
+// must fail with NewProjectEvaluator too.
+// Both run in the directory of the project, and occurrences of {{server}} within a PklProject are
+// replaced with the address of an HTTP server, which responds with the path and X-Greeting header of
+// every request.
+func TestNewProjectEvaluator_conformance(t *testing.T) {
+	projectsDir, err := filepath.Abs("test_fixtures/projects")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := os.ReadDir(projectsDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, "%s %s", r.URL.Path, r.Header.Get("X-Greeting"))
+	}))
+	defer server.Close()
+	command, args := (&execEvaluator{}).getCommandAndArgStrings()
+	for _, entry := range entries {
+		t.Run(entry.Name(), func(t *testing.T) {
+			projectDir := filepath.Join(projectsDir, entry.Name())
+			if project, err := os.ReadFile(filepath.Join(projectDir, "PklProject")); err != nil {
+				t.Fatal(err)
+			} else if strings.Contains(string(project), "{{server}}") {
+				projectDir = copyProject(t, projectDir, strings.ReplaceAll(string(project), "{{server}}", server.URL))
+			}
+			mainModule := filepath.Join(projectDir, "main.pkl")
+			cmd := exec.Command(command, append(args, "eval", "--project-dir", projectDir, mainModule)...)
+			cmd.Dir = projectDir
+			expected, cliErr := cmd.Output()
+			var exitErr *exec.ExitError
+			if cliErr != nil && !errors.As(cliErr, &exitErr) {
+				t.Fatalf("failed to run the CLI: %v", cliErr)
+			}
+			manager := NewEvaluatorManagerWithOptions(WithProcessOptions(ProcessOptions{WorkingDir: projectDir}))
+			defer func() { assert.NoError(t, manager.Close()) }()
+			ev, err := manager.NewProjectEvaluator(context.Background(), &url.URL{Scheme: "file", Path: projectDir}, WithCliDefaults)
+			if !assert.NoError(t, err) {
+				return
+			}
+			actual, err := ev.EvaluateOutputText(context.Background(), FileSource(mainModule))
+			if cliErr != nil {
+				assert.Error(t, err, "the CLI failed with: %v", cliErr)
+				return
+			}
+			if assert.NoError(t, err) {
+				assert.Equal(t, string(expected), actual)
+			}
+		})
+	}
+}
+
+// copyProject copies the files of projectDir to a temporary directory, with the given PklProject.
+func copyProject(t *testing.T, projectDir string, pklProject string) string {
+	t.Helper()
+	dir := t.TempDir()
+	entries, err := os.ReadDir(projectDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		contents, err := os.ReadFile(filepath.Join(projectDir, entry.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if entry.Name() == "PklProject" {
+			contents = []byte(pklProject)
+		}
+		if err = os.WriteFile(filepath.Join(dir, entry.Name()), contents, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
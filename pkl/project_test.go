@@ -22,8 +22,10 @@ import (
 	"net/http"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/apple/pkl-go/pkl/internal"
+	"github.com/apple/pkl-go/pkl/internal/msgapi"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -492,3 +494,68 @@ func TestLoadProjectWithHeaders(t *testing.T) {
 		assert.Equal(t, expectedOptions, opts)
 	})
 }
+
+func TestWithProjectEvaluatorSettings_Timeout(t *testing.T) {
+	project := &Project{
+		ResolvedEvaluatorSettings: ProjectEvaluatorSettings{
+			Timeout: Duration{Value: 1.5, Unit: Minute},
+		},
+	}
+	opts := &EvaluatorOptions{}
+	WithProjectEvaluatorSettings(project)(opts)
+	assert.Equal(t, 90*time.Second, opts.Timeout)
+	assert.Equal(t, int64(90), opts.toMessage().TimeoutSeconds)
+}
+
+func TestWithProjectEvaluatorSettingsLegacy(t *testing.T) {
+	fals := false
+	project := &Project{
+		ProjectFileUri: "file:///srv/project/PklProject",
+		EvaluatorSettings: ProjectEvaluatorSettings{
+			NoCache:    &fals,
+			RootDir:    ".",
+			ModulePath: []string{"lib", "/opt/pkl/lib"},
+			ExternalResourceReaders: map[string]ProjectEvaluatorSettingExternalReader{
+				"secret": {Executable: "secret-reader", WorkingDir: "/srv"},
+			},
+		},
+	}
+	opts := &EvaluatorOptions{CacheDir: "/home/user/.pkl/cache", Env: map[string]string{"HOME": "/home/user"}}
+	WithProjectEvaluatorSettingsLegacy(project)(opts)
+	assert.Equal(t, "/srv/project", opts.RootDir)
+	assert.Equal(t, []string{"/srv/project/lib", "/opt/pkl/lib"}, opts.ModulePaths)
+	// settings that are not set keep the existing options.
+	assert.Equal(t, "/home/user/.pkl/cache", opts.CacheDir)
+	assert.Equal(t, map[string]string{"HOME": "/home/user"}, opts.Env)
+	// the project itself is left untouched.
+	assert.Equal(t, []string{"lib", "/opt/pkl/lib"}, project.EvaluatorSettings.ModulePath)
+
+	msg := opts.toMessage()
+	assert.Equal(t, []string{"/srv/project/lib", "/opt/pkl/lib"}, msg.ModulePaths)
+	assert.Equal(t, &msgapi.ExternalReader{Executable: "secret-reader", WorkingDir: "/srv"}, msg.ExternalResourceReaders["secret"])
+}
+
+func TestWithProjectEvaluatorSettings_invalidHeaders(t *testing.T) {
+	project := &Project{
+		ResolvedEvaluatorSettings: ProjectEvaluatorSettings{
+			Http: &ProjectEvaluatorSettingsHttp{
+				Headers: &map[string]map[string]any{"**": {"X-Number": 1}},
+			},
+		},
+	}
+	opts := &EvaluatorOptions{}
+	WithProjectEvaluatorSettings(project)(opts)
+	assert.EqualError(t, opts.Err(), `unexpected value of type int in project at evaluatorSettings.http.headers["**"]["X-Number"]`)
+}
+
+func TestWithHttpHeaders_nilHttp(t *testing.T) {
+	opts := &EvaluatorOptions{}
+	WithHttpHeaders("**", http.Header{"X-One-Value": {"foo"}})(opts)
+	assert.Equal(t, map[string]http.Header{"**": {"X-One-Value": {"foo"}}}, opts.Http.Headers)
+}
+
+func TestWithCliDefaults(t *testing.T) {
+	assert.False(t, usesCliDefaults(nil))
+	assert.False(t, usesCliDefaults([]func(*EvaluatorOptions){PreconfiguredOptions}))
+	assert.True(t, usesCliDefaults([]func(*EvaluatorOptions){WithOsEnv, WithCliDefaults}))
+}
@@ -0,0 +1,226 @@
+//===----------------------------------------------------------------------===//
+// Copyright © 2026 Apple Inc. and the Pkl project authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//===----------------------------------------------------------------------===//
+
+package pkl
+
+import (
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/apple/pkl-go/pkl/internal/msgapi"
+)
+
+// ReaderDispatchOptions controls how an EvaluatorManager runs the calls from Pkl into the readers
+// of its evaluators.
+//
+// Calls run concurrently, so that a slow reader does not hold up the other evaluators of the
+// manager.
+type ReaderDispatchOptions struct {
+	// MaxConcurrency is the maximum number of reader calls that run at once.
+	// Once reached, the manager stops reading messages from Pkl until a call completes.
+	//
+	// Calls that wait for the limit of their scheme do not count towards MaxConcurrency.
+	//
+	// If zero, defaults to 32.
+	MaxConcurrency int
+
+	// MaxConcurrencyPerScheme is the maximum number of calls that run at once for the readers of
+	// a scheme.
+	// Further calls for the scheme are queued, without holding up calls for other schemes.
+	//
+	// Schemes that are not listed are only limited by MaxConcurrency.
+	MaxConcurrencyPerScheme map[string]int
+
+	// Timeout bounds how long a single reader call may take.
+	// Once it elapses, Pkl receives an error for the call.
+	// The reader keeps running in the background, and counts towards the limits until it returns.
+	//
+	// If zero, reader calls never time out.
+	Timeout time.Duration
+}
+
+// WithReaderDispatch sets how the EvaluatorManager runs the calls from Pkl into readers.
+var WithReaderDispatch = func(dispatch ReaderDispatchOptions) func(opts *EvaluatorManagerOptions) {
+	return func(opts *EvaluatorManagerOptions) {
+		opts.ReaderDispatch = dispatch
+	}
+}
+
+const defaultReaderConcurrency = 32
+
+// readerDispatcher runs reader calls on their own goroutines, and sends their responses to Pkl.
+type readerDispatcher struct {
+	timeout time.Duration
+	// sem holds a token for every call in progress.
+	sem chan struct{}
+	// schemes holds the queues of the schemes with a concurrency limit.
+	schemes map[string]*schemeQueue
+	// stop is closed once responses must no longer be sent.
+	stop     chan struct{}
+	stopOnce sync.Once
+
+	// mu is held for reading while sending a response, and for writing while stopping.
+	mu      sync.RWMutex
+	stopped bool
+}
+
+// schemeQueue holds the calls for a scheme that wait for its concurrency limit.
+type schemeQueue struct {
+	limit int
+
+	// mu guards running and pending.
+	mu      sync.Mutex
+	running int
+	pending []func()
+}
+
+func newReaderDispatcher(o ReaderDispatchOptions) *readerDispatcher {
+	maxConcurrency := o.MaxConcurrency
+	if maxConcurrency <= 0 {
+		maxConcurrency = defaultReaderConcurrency
+	}
+	d := &readerDispatcher{
+		timeout: o.Timeout,
+		sem:     make(chan struct{}, maxConcurrency),
+		schemes: make(map[string]*schemeQueue, len(o.MaxConcurrencyPerScheme)),
+		stop:    make(chan struct{}),
+	}
+	for scheme, limit := range o.MaxConcurrencyPerScheme {
+		if limit > 0 {
+			d.schemes[scheme] = &schemeQueue{limit: limit}
+		}
+	}
+	return d
+}
+
+// dispatch calls the reader of ev for uri, and sends the response built by toMessage to out.
+//
+// It blocks while the maximum number of calls are in progress, but not while the scheme of uri is
+// at its limit; the call is then queued instead.
+func (d *readerDispatcher) dispatch(
+	ev *evaluator,
+	operation ReaderOperation,
+	uri string,
+	toMessage func(result ReaderResponse) msgapi.OutgoingMessage,
+	out chan msgapi.OutgoingMessage,
+) {
+	run := func() {
+		d.call(ev, operation, uri, func(resp ReaderResponse) {
+			d.send(out, toMessage(resp))
+		})
+	}
+	var queue *schemeQueue
+	if u, err := url.Parse(uri); err == nil {
+		queue = d.schemes[u.Scheme]
+	}
+	if queue == nil {
+		d.start(run, nil)
+		return
+	}
+	queue.mu.Lock()
+	if queue.running == queue.limit {
+		queue.pending = append(queue.pending, run)
+		queue.mu.Unlock()
+		return
+	}
+	queue.running++
+	queue.mu.Unlock()
+	d.start(run, queue)
+}
+
+// start runs call on its own goroutine once a slot is free, and then runs the next call that is
+// queued for its scheme, if any.
+func (d *readerDispatcher) start(call func(), queue *schemeQueue) {
+	d.sem <- empty
+	go func() {
+		call()
+		<-d.sem
+		if queue != nil {
+			if next := d.next(queue); next != nil {
+				d.start(next, queue)
+			}
+		}
+	}()
+}
+
+// next takes the next queued call of queue, or frees its slot if there is none.
+func (d *readerDispatcher) next(queue *schemeQueue) func() {
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+	select {
+	case <-d.stop:
+		// responses can no longer be sent, so there is no point in calling readers.
+		queue.pending = nil
+	default:
+	}
+	if len(queue.pending) == 0 {
+		queue.running--
+		return nil
+	}
+	next := queue.pending[0]
+	queue.pending[0] = nil
+	queue.pending = queue.pending[1:]
+	return next
+}
+
+// call calls the reader of ev, and passes its response to respond.
+//
+// Once the timeout elapses, respond receives an error instead, but call only returns once the
+// reader does, so that readers that time out still count towards the limits.
+func (d *readerDispatcher) call(ev *evaluator, operation ReaderOperation, uri string, respond func(ReaderResponse)) {
+	if d.timeout <= 0 {
+		respond(ev.dispatchRead(operation, uri))
+		return
+	}
+	result := make(chan ReaderResponse, 1)
+	go func() {
+		result <- ev.dispatchRead(operation, uri)
+	}()
+	timer := time.NewTimer(d.timeout)
+	defer timer.Stop()
+	select {
+	case resp := <-result:
+		respond(resp)
+	case <-timer.C:
+		respond(ReaderResponse{Err: fmt.Errorf("reading `%s` timed out after %s", uri, d.timeout)})
+		<-result
+	}
+}
+
+// send sends msg to out, unless the dispatcher is stopped.
+func (d *readerDispatcher) send(out chan msgapi.OutgoingMessage, msg msgapi.OutgoingMessage) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.stopped {
+		return
+	}
+	select {
+	case out <- msg:
+	case <-d.stop:
+	}
+}
+
+// close stops sending responses, and waits for responses that are being sent.
+//
+// Once it returns, the outgoing channel can be closed safely.
+func (d *readerDispatcher) close() {
+	d.stopOnce.Do(func() { close(d.stop) })
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.stopped = true
+}
@@ -0,0 +1,195 @@
+//===----------------------------------------------------------------------===//
+// Copyright © 2026 Apple Inc. and the Pkl project authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//===----------------------------------------------------------------------===//
+
+package pkl_test
+
+import (
+	"context"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/apple/pkl-go/pkl"
+	"github.com/apple/pkl-go/pkl/pkltest"
+	"github.com/stretchr/testify/assert"
+)
+
+// blockingResourceReader is a resource reader whose reads wait until release is closed.
+type blockingResourceReader struct {
+	scheme  string
+	release chan struct{}
+	running atomic.Int32
+	maxSeen atomic.Int32
+}
+
+func (r *blockingResourceReader) Scheme() string {
+	return r.scheme
+}
+
+func (r *blockingResourceReader) IsGlobbable() bool {
+	return false
+}
+
+func (r *blockingResourceReader) HasHierarchicalUris() bool {
+	return false
+}
+
+func (r *blockingResourceReader) ListElements(url.URL) ([]pkl.PathElement, error) {
+	return nil, nil
+}
+
+func (r *blockingResourceReader) Read(uri url.URL) ([]byte, error) {
+	running := r.running.Add(1)
+	defer r.running.Add(-1)
+	for {
+		maxSeen := r.maxSeen.Load()
+		if running <= maxSeen || r.maxSeen.CompareAndSwap(maxSeen, running) {
+			break
+		}
+	}
+	if r.release != nil {
+		<-r.release
+	}
+	return []byte(uri.String()), nil
+}
+
+// newReadingEvaluator creates an evaluator with readers, whose evaluations read the resource at
+// the URI given by their expression.
+func newReadingEvaluator(t *testing.T, dispatch pkl.ReaderDispatchOptions, readers ...pkl.ResourceReader) pkl.Evaluator {
+	server := pkltest.NewServer()
+	server.HandleEvaluate(func(call *pkltest.Call) ([]byte, error) {
+		contents, err := call.ReadResource(call.Expr)
+		if err != nil {
+			return nil, err
+		}
+		return pkltest.Encode(string(contents))
+	})
+	manager := newServerManager(t, server, pkl.WithReaderDispatch(dispatch))
+	var opts []func(options *pkl.EvaluatorOptions)
+	for _, reader := range readers {
+		opts = append(opts, pkl.WithResourceReader(reader))
+	}
+	ev, err := manager.NewEvaluator(context.Background(), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ev
+}
+
+type readResult struct {
+	out []byte
+	err error
+}
+
+// read starts reading uri with ev.
+func read(ev pkl.Evaluator, uri string) chan readResult {
+	result := make(chan readResult, 1)
+	go func() {
+		out, err := ev.EvaluateExpressionRaw(context.Background(), pkl.TextSource(""), uri)
+		result <- readResult{out, err}
+	}()
+	return result
+}
+
+func TestReaderDispatch_slowReader(t *testing.T) {
+	slow := &blockingResourceReader{scheme: "slow", release: make(chan struct{})}
+	fast := &blockingResourceReader{scheme: "fast"}
+	ev := newReadingEvaluator(t, pkl.ReaderDispatchOptions{}, slow, fast)
+
+	second := read(ev, "slow:a")
+	assert.Eventually(t, func() bool { return slow.running.Load() == 1 }, time.Second, time.Millisecond)
+	first := <-read(ev, "fast:b")
+	assert.NoError(t, first.err)
+	assert.Equal(t, pkltest.MustEncode("fast:b"), first.out)
+	assert.Empty(t, second)
+
+	close(slow.release)
+	result := <-second
+	assert.NoError(t, result.err)
+	assert.Equal(t, pkltest.MustEncode("slow:a"), result.out)
+}
+
+func TestReaderDispatch_MaxConcurrencyPerScheme(t *testing.T) {
+	slow := &blockingResourceReader{scheme: "slow", release: make(chan struct{})}
+	ev := newReadingEvaluator(t, pkl.ReaderDispatchOptions{
+		MaxConcurrencyPerScheme: map[string]int{"slow": 2},
+	}, slow)
+
+	var results []chan readResult
+	for range 5 {
+		results = append(results, read(ev, "slow:a"))
+	}
+	assert.Eventually(t, func() bool { return slow.running.Load() == 2 }, time.Second, time.Millisecond)
+	close(slow.release)
+	for _, result := range results {
+		assert.NoError(t, (<-result).err)
+	}
+	assert.Equal(t, int32(2), slow.maxSeen.Load())
+}
+
+func TestReaderDispatch_queuedSchemeDoesNotBlock(t *testing.T) {
+	slow := &blockingResourceReader{scheme: "slow", release: make(chan struct{})}
+	fast := &blockingResourceReader{scheme: "fast"}
+	ev := newReadingEvaluator(t, pkl.ReaderDispatchOptions{
+		MaxConcurrency:          2,
+		MaxConcurrencyPerScheme: map[string]int{"slow": 1},
+	}, slow, fast)
+
+	// calls waiting for the limit of `slow` hold no slots, so `fast` still gets through.
+	var results []chan readResult
+	for range 5 {
+		results = append(results, read(ev, "slow:a"))
+	}
+	assert.Eventually(t, func() bool { return slow.running.Load() == 1 }, time.Second, time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	first := <-read(ev, "fast:b")
+	assert.NoError(t, first.err)
+
+	close(slow.release)
+	for _, result := range results {
+		assert.NoError(t, (<-result).err)
+	}
+	assert.Equal(t, int32(1), slow.maxSeen.Load())
+}
+
+func TestReaderDispatch_Timeout(t *testing.T) {
+	slow := &blockingResourceReader{scheme: "slow", release: make(chan struct{})}
+	defer close(slow.release)
+	ev := newReadingEvaluator(t, pkl.ReaderDispatchOptions{Timeout: 10 * time.Millisecond}, slow)
+
+	result := <-read(ev, "slow:a")
+	assert.Nil(t, result.out)
+	assert.ErrorContains(t, result.err, "reading `slow:a` timed out after 10ms")
+}
+
+func TestReaderDispatch_TimeoutCountsTowardsLimit(t *testing.T) {
+	slow := &blockingResourceReader{scheme: "slow", release: make(chan struct{})}
+	fast := &blockingResourceReader{scheme: "fast"}
+	ev := newReadingEvaluator(t, pkl.ReaderDispatchOptions{MaxConcurrency: 1, Timeout: 10 * time.Millisecond}, slow, fast)
+
+	assert.Error(t, (<-read(ev, "slow:a")).err)
+
+	// the slow reader still runs, so the next call waits for it.
+	second := read(ev, "fast:b")
+	select {
+	case result := <-second:
+		t.Fatalf("unexpected result %v", result)
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(slow.release)
+	assert.NoError(t, (<-second).err)
+}
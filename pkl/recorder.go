@@ -0,0 +1,207 @@
+//===----------------------------------------------------------------------===//
+// Copyright © 2026 Apple Inc. and the Pkl project authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//===----------------------------------------------------------------------===//
+
+package pkl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// fixtureVersion is the version of the fixture format written by Recorder.
+const fixtureVersion = 1
+
+// Fixture is a recording of the traffic of evaluators, as captured by a Recorder.
+//
+// Fixtures are stored as JSON, and are replayed by NewReplayEvaluator.
+type Fixture struct {
+	// Version is the version of the fixture format.
+	Version int `json:"version"`
+
+	// Interactions are the recorded evaluations, in the order that they completed.
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is a single recorded evaluation.
+type Interaction struct {
+	ModuleUri  string `json:"moduleUri"`
+	ModuleText string `json:"moduleText,omitempty"`
+	Expr       string `json:"expr,omitempty"`
+
+	// Result is the raw pkl-binary result of the evaluation.
+	Result []byte `json:"result,omitempty"`
+
+	// Error is the error output of the evaluation, if it failed.
+	Error string `json:"error,omitempty"`
+
+	// Reads are the calls from Pkl into readers during the evaluation.
+	Reads []RecordedRead `json:"reads,omitempty"`
+
+	// Logs are the messages logged by Pkl during the evaluation.
+	Logs []RecordedLog `json:"logs,omitempty"`
+}
+
+// RecordedLog is a recorded log message.
+type RecordedLog struct {
+	Level    LogLevel `json:"level"`
+	Message  string   `json:"message"`
+	FrameUri string   `json:"frameUri,omitempty"`
+}
+
+// RecordedRead is a recorded call from Pkl into a reader.
+type RecordedRead struct {
+	Operation    ReaderOperation       `json:"operation"`
+	Uri          string                `json:"uri"`
+	Contents     []byte                `json:"contents,omitempty"`
+	PathElements []RecordedPathElement `json:"pathElements,omitempty"`
+	Error        string                `json:"error,omitempty"`
+
+	// NotFound tells if the reader responded with ResourceNotFound.
+	NotFound bool `json:"notFound,omitempty"`
+}
+
+// RecordedPathElement is a PathElement within a RecordedRead.
+type RecordedPathElement struct {
+	Name        string `json:"name"`
+	IsDirectory bool   `json:"isDirectory,omitempty"`
+}
+
+// Recorder captures the evaluations of evaluators, along with the reader calls and log messages
+// that they cause, into a Fixture.
+//
+// Reader calls and log messages are attributed to the evaluations in flight on the same
+// evaluator. For an accurate recording, evaluations on an evaluator should not overlap.
+type Recorder struct {
+	mu           sync.Mutex
+	interactions []Interaction
+}
+
+// NewRecorder creates a new, empty Recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// WithRecorder records the traffic of the evaluator into recorder.
+var WithRecorder = func(recorder *Recorder) func(opts *EvaluatorOptions) {
+	return WithInterceptor(recorder.interceptor())
+}
+
+// Fixture returns the interactions recorded so far.
+func (r *Recorder) Fixture() *Fixture {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return &Fixture{Version: fixtureVersion, Interactions: append([]Interaction(nil), r.interactions...)}
+}
+
+// Save writes the interactions recorded so far to a fixture file at path.
+func (r *Recorder) Save(path string) error {
+	b, err := json.MarshalIndent(r.Fixture(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0o644)
+}
+
+// LoadFixture reads a fixture file written by Recorder.Save.
+func LoadFixture(path string) (*Fixture, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var fixture Fixture
+	if err = json.Unmarshal(b, &fixture); err != nil {
+		return nil, fmt.Errorf("failed to parse fixture %s: %w", path, err)
+	}
+	if fixture.Version != fixtureVersion {
+		return nil, fmt.Errorf("fixture %s has version %d, but only version %d is supported; re-record it", path, fixture.Version, fixtureVersion)
+	}
+	return &fixture, nil
+}
+
+// interceptor returns an interceptor that records the traffic of a single evaluator.
+func (r *Recorder) interceptor() Interceptor {
+	var mu sync.Mutex
+	inFlight := make(map[*Interaction]struct{})
+	attribute := func(f func(interaction *Interaction)) {
+		mu.Lock()
+		defer mu.Unlock()
+		for interaction := range inFlight {
+			f(interaction)
+		}
+	}
+	return Interceptor{
+		Evaluate: func(ctx context.Context, req EvaluateRequest, next EvaluateHandler) ([]byte, error) {
+			interaction := &Interaction{ModuleUri: req.Source.Uri.String(), ModuleText: req.Source.Contents, Expr: req.Expr}
+			mu.Lock()
+			inFlight[interaction] = struct{}{}
+			mu.Unlock()
+			result, err := next(ctx, req)
+			mu.Lock()
+			delete(inFlight, interaction)
+			mu.Unlock()
+			if err != nil {
+				var evalError *EvalError
+				if !errors.As(err, &evalError) {
+					// only evaluation errors are reproducible.
+					return result, err
+				}
+				interaction.Error = evalError.ErrorOutput
+			} else {
+				interaction.Result = result
+			}
+			r.mu.Lock()
+			r.interactions = append(r.interactions, *interaction)
+			r.mu.Unlock()
+			return result, err
+		},
+		Log: func(msg LogMessage, next LogHandler) {
+			attribute(func(interaction *Interaction) {
+				interaction.Logs = append(interaction.Logs, RecordedLog{Level: msg.Level, Message: msg.Message, FrameUri: msg.FrameUri})
+			})
+			next(msg)
+		},
+		Reader: func(req ReaderRequest, next ReaderHandler) ReaderResponse {
+			resp := next(req)
+			read := RecordedRead{
+				Operation:    req.Operation,
+				Uri:          req.Uri.String(),
+				Contents:     resp.Contents,
+				PathElements: toRecordedPathElements(resp.PathElements),
+			}
+			if resp.Err == ResourceNotFound {
+				read.NotFound = true
+			} else if resp.Err != nil {
+				read.Error = resp.Err.Error()
+			}
+			attribute(func(interaction *Interaction) {
+				interaction.Reads = append(interaction.Reads, read)
+			})
+			return resp
+		},
+	}
+}
+
+func toRecordedPathElements(pathElements []PathElement) []RecordedPathElement {
+	var ret []RecordedPathElement
+	for _, elem := range pathElements {
+		ret = append(ret, RecordedPathElement{Name: elem.Name(), IsDirectory: elem.IsDirectory()})
+	}
+	return ret
+}
@@ -0,0 +1,178 @@
+//===----------------------------------------------------------------------===//
+// Copyright © 2026 Apple Inc. and the Pkl project authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//===----------------------------------------------------------------------===//
+
+package pkl_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/apple/pkl-go/pkl"
+	"github.com/apple/pkl-go/pkl/pkltest"
+	"github.com/stretchr/testify/assert"
+)
+
+// secretReader reads the resource `secret:password`.
+type secretReader struct {
+	secret *string
+}
+
+func (r secretReader) Scheme() string {
+	return "secret"
+}
+
+func (r secretReader) IsGlobbable() bool {
+	return false
+}
+
+func (r secretReader) HasHierarchicalUris() bool {
+	return false
+}
+
+func (r secretReader) ListElements(url.URL) ([]pkl.PathElement, error) {
+	return nil, nil
+}
+
+func (r secretReader) Read(url.URL) ([]byte, error) {
+	return []byte(*r.secret), nil
+}
+
+var _ pkl.ResourceReader = secretReader{}
+
+func recordFixture(t *testing.T, secret *string) string {
+	server := pkltest.NewServer()
+	server.HandleEvaluate(func(call *pkltest.Call) ([]byte, error) {
+		if call.Expr == "output.text" {
+			return nil, pkltest.Errorf("boom")
+		}
+		if err := call.Log(pkl.LogLevelWarn, "careful", "repl:text"); err != nil {
+			return nil, err
+		}
+		if _, err := call.ReadResource("secret:password"); err != nil {
+			return nil, err
+		}
+		return pkltest.Encode("foo")
+	})
+	manager := newServerManager(t, server)
+
+	recorder := pkl.NewRecorder()
+	ev, err := manager.NewEvaluator(context.Background(),
+		pkl.WithResourceReader(secretReader{secret}),
+		func(opts *pkl.EvaluatorOptions) { opts.Logger = pkl.NoopLogger },
+		pkl.WithRecorder(recorder),
+	)
+	if !assert.NoError(t, err) {
+		return ""
+	}
+	var out string
+	assert.NoError(t, ev.EvaluateExpression(context.Background(), pkl.TextSource(`foo = read("secret:password").text`), "foo", &out))
+	assert.Equal(t, "foo", out)
+	_, err = ev.EvaluateOutputText(context.Background(), pkl.TextSource("bar = throw(\"boom\")"))
+	assert.Error(t, err)
+
+	path := filepath.Join(t.TempDir(), "fixture.json")
+	assert.NoError(t, recorder.Save(path))
+	return path
+}
+
+func TestRecorder(t *testing.T) {
+	secret := "hunter2"
+	path := recordFixture(t, &secret)
+	fixture, err := pkl.LoadFixture(path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, 1, fixture.Version)
+	if !assert.Len(t, fixture.Interactions, 2) {
+		return
+	}
+	first := fixture.Interactions[0]
+	assert.Equal(t, "repl:text", first.ModuleUri)
+	assert.Equal(t, "foo", first.Expr)
+	assert.Equal(t, pkltest.MustEncode("foo"), first.Result)
+	assert.Equal(t, []pkl.RecordedRead{{Operation: pkl.ReadResource, Uri: "secret:password", Contents: []byte("hunter2")}}, first.Reads)
+	assert.Equal(t, []pkl.RecordedLog{{Level: pkl.LogLevelWarn, Message: "careful", FrameUri: "repl:text"}}, first.Logs)
+	assert.Equal(t, "output.text", fixture.Interactions[1].Expr)
+	assert.Equal(t, "–– Pkl Error ––\nboom", fixture.Interactions[1].Error)
+}
+
+func TestReplayEvaluator(t *testing.T) {
+	secret := "hunter2"
+	path := recordFixture(t, &secret)
+	var buf bytes.Buffer
+	ev, err := pkl.NewReplayEvaluator(path, pkl.WithResourceReader(secretReader{&secret}), func(opts *pkl.EvaluatorOptions) {
+		opts.Logger = pkl.NewLogger(&buf)
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer func() { assert.NoError(t, ev.Close()) }()
+
+	t.Run("recorded result", func(t *testing.T) {
+		var out string
+		assert.NoError(t, ev.EvaluateExpression(context.Background(), pkl.TextSource(`foo = read("secret:password").text`), "foo", &out))
+		assert.Equal(t, "foo", out)
+		assert.Contains(t, buf.String(), "careful")
+	})
+
+	t.Run("recorded error", func(t *testing.T) {
+		_, err := ev.EvaluateOutputText(context.Background(), pkl.TextSource("bar = throw(\"boom\")"))
+		var evalErr *pkl.EvalError
+		assert.True(t, errors.As(err, &evalErr))
+		assert.Equal(t, "boom", evalErr.Message)
+	})
+
+	t.Run("unrecorded request", func(t *testing.T) {
+		_, err := ev.EvaluateOutputText(context.Background(), pkl.TextSource("bar = 1"))
+		var mismatchErr *pkl.ReplayMismatchError
+		if assert.ErrorAs(t, err, &mismatchErr) {
+			assert.Equal(t, "module text differs from the recording", mismatchErr.Reason)
+		}
+
+		_, err = ev.EvaluateExpressionRaw(context.Background(), pkl.TextSource("baz = 1"), "baz")
+		assert.ErrorContains(t, err, "request was not recorded")
+	})
+
+	t.Run("changed read", func(t *testing.T) {
+		secret = "correct horse battery staple"
+		_, err := ev.EvaluateExpressionRaw(context.Background(), pkl.TextSource(`foo = read("secret:password").text`), "foo")
+		var mismatchErr *pkl.ReplayMismatchError
+		assert.ErrorAs(t, err, &mismatchErr)
+		assert.ErrorContains(t, err, "contents of secret:password changed since the recording")
+	})
+
+	// mismatches are recorded too, including those of unrecorded requests.
+	stats, ok := pkl.EvaluatorStats(ev)
+	assert.True(t, ok)
+	assert.Equal(t, int64(5), stats.Evaluations)
+	assert.Equal(t, int64(3), stats.Errors["replayMismatch"])
+}
+
+func TestLoadFixture_unsupportedVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fixture.json")
+	assert.NoError(t, (&pkl.Recorder{}).Save(path))
+	_, err := pkl.LoadFixture(path)
+	assert.NoError(t, err)
+
+	assert.NoError(t, os.WriteFile(path, []byte(`{"version": 99, "interactions": []}`), 0o644))
+	_, err = pkl.LoadFixture(path)
+	assert.ErrorContains(t, err, "has version 99")
+}
@@ -0,0 +1,314 @@
+//===----------------------------------------------------------------------===//
+// Copyright © 2026 Apple Inc. and the Pkl project authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//===----------------------------------------------------------------------===//
+
+package pkl
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+	"sync"
+)
+
+// NewReplayEvaluator returns an evaluator that serves the interactions recorded in the fixture
+// file at path, without running Pkl.
+//
+// Requests are matched by module URI, module text and expression. If the same request was
+// recorded more than once, its recordings are served in order, and the last one is repeated.
+//
+// Log messages are replayed to the Logger set in the options. Recorded reads are performed again
+// against the readers set in the options that handle their scheme; readers for other schemes are
+// assumed not to have changed.
+//
+// A request that no longer matches the fixture fails with *ReplayMismatchError, which means that the
+// fixture needs to be recorded again.
+func NewReplayEvaluator(path string, opts ...func(options *EvaluatorOptions)) (Evaluator, error) {
+	fixture, err := LoadFixture(path)
+	if err != nil {
+		return nil, err
+	}
+	o := &EvaluatorOptions{}
+	for _, f := range opts {
+		f(o)
+	}
+	if err := o.Err(); err != nil {
+		return nil, err
+	}
+	if o.Logger == nil {
+		o.Logger = NoopLogger
+	}
+	e := &replayEvaluator{options: o, interactions: make(map[replayKey][]*Interaction), served: make(map[replayKey]int)}
+	for i := range fixture.Interactions {
+		interaction := &fixture.Interactions[i]
+		key := replayKey{interaction.ModuleUri, interaction.ModuleText, interaction.Expr}
+		e.interactions[key] = append(e.interactions[key], interaction)
+	}
+	e.metrics = append(metricsRecorders{&e.stats}, o.MetricsRecorders...)
+	return e, nil
+}
+
+// NewFixtureEvaluator returns an evaluator backed by the fixture file at path.
+//
+// If record is true, a new evaluator is created with NewEvaluator, and its traffic gets saved to
+// path when the evaluator is closed. Otherwise, the fixture is replayed with NewReplayEvaluator.
+//
+// Typically, record is set from a flag or environment variable, so that fixtures can be refreshed
+// by running tests with that flag set whenever the Pkl sources change.
+func NewFixtureEvaluator(ctx context.Context, path string, record bool, opts ...func(options *EvaluatorOptions)) (Evaluator, error) {
+	if !record {
+		return NewReplayEvaluator(path, opts...)
+	}
+	recorder := NewRecorder()
+	ev, err := NewEvaluator(ctx, append(slices.Clone(opts), WithRecorder(recorder))...)
+	if err != nil {
+		return nil, err
+	}
+	return &recordingEvaluator{Evaluator: ev, recorder: recorder, path: path}, nil
+}
+
+// ReplayMismatchError is returned by replay evaluators when a request does not match the recorded
+// fixture.
+//
+// Use errors.As to check for it.
+type ReplayMismatchError struct {
+	ModuleUri string
+	Expr      string
+
+	// Reason describes what no longer matches.
+	Reason string
+}
+
+var _ error = (*ReplayMismatchError)(nil)
+
+func (r *ReplayMismatchError) Error() string {
+	return fmt.Sprintf("replay mismatch for module %s (expression %q): %s; the fixture needs to be recorded again", r.ModuleUri, r.Expr, r.Reason)
+}
+
+type replayKey struct {
+	moduleUri  string
+	moduleText string
+	expr       string
+}
+
+type replayEvaluator struct {
+	options      *EvaluatorOptions
+	stats        statsCollector
+	metrics      metricsRecorders
+	mu           sync.Mutex
+	interactions map[replayKey][]*Interaction
+	served       map[replayKey]int
+	closed       atomicBool
+}
+
+var _ Evaluator = (*replayEvaluator)(nil)
+
+func (e *replayEvaluator) EvaluateModule(ctx context.Context, source *ModuleSource, out any) error {
+	return e.EvaluateExpression(ctx, source, "", out)
+}
+
+func (e *replayEvaluator) EvaluateOutputText(ctx context.Context, source *ModuleSource) (string, error) {
+	var out string
+	err := e.EvaluateExpression(ctx, source, exprOutputText, &out)
+	return out, err
+}
+
+func (e *replayEvaluator) EvaluateOutputBytes(ctx context.Context, source *ModuleSource) ([]byte, error) {
+	var out []byte
+	err := e.EvaluateExpression(ctx, source, exprOutputBytes, &out)
+	return out, err
+}
+
+func (e *replayEvaluator) EvaluateOutputValue(ctx context.Context, source *ModuleSource, out any) error {
+	return e.EvaluateExpression(ctx, source, exprOutputValue, out)
+}
+
+func (e *replayEvaluator) EvaluateOutputFiles(ctx context.Context, source *ModuleSource) (map[string]string, error) {
+	var out map[string]string
+	err := e.EvaluateExpression(ctx, source, exprOutputFiles, &out)
+	return out, err
+}
+
+func (e *replayEvaluator) EvaluateOutputFilesBytes(ctx context.Context, source *ModuleSource) (map[string][]byte, error) {
+	var out map[string][]byte
+	err := e.EvaluateExpression(ctx, source, exprOutputFilesBytes, &out)
+	return out, err
+}
+
+func (e *replayEvaluator) EvaluateExpression(ctx context.Context, source *ModuleSource, expr string, out any) error {
+	bytes, err := e.EvaluateExpressionRaw(ctx, source, expr)
+	if err != nil {
+		return err
+	}
+	return Unmarshal(bytes, out)
+}
+
+func (e *replayEvaluator) EvaluateExpressionRaw(ctx context.Context, source *ModuleSource, expr string) ([]byte, error) {
+	return chainEvaluate(e.options.Interceptors, e.replay)(ctx, EvaluateRequest{Source: source, Expr: expr})
+}
+
+func (e *replayEvaluator) Stats() Stats {
+	return e.stats.snapshot()
+}
+
+func (e *replayEvaluator) Close() error {
+	e.closed.set(true)
+	return nil
+}
+
+func (e *replayEvaluator) Closed() bool {
+	return e.closed.get()
+}
+
+func (e *replayEvaluator) replay(ctx context.Context, req EvaluateRequest) (result []byte, err error) {
+	if e.Closed() {
+		return nil, ErrEvaluatorClosed
+	}
+	defer func() {
+		metric := EvaluationMetric{Bytes: len(result), Err: err}
+		if err != nil {
+			metric.ErrorKind = errorKindOf(err)
+		}
+		e.metrics.RecordEvaluation(metric)
+	}()
+	if err = ctx.Err(); err != nil {
+		return nil, &CanceledError{Err: err}
+	}
+	interaction, err := e.next(req)
+	if err != nil {
+		return nil, err
+	}
+	for _, read := range interaction.Reads {
+		if reason := e.verifyRead(read); reason != "" {
+			return nil, &ReplayMismatchError{ModuleUri: interaction.ModuleUri, Expr: interaction.Expr, Reason: reason}
+		}
+	}
+	for _, log := range interaction.Logs {
+		chainLog(e.options.Interceptors, e.log)(LogMessage{Level: log.Level, Message: log.Message, FrameUri: log.FrameUri})
+	}
+	if interaction.Error != "" {
+		evalErr := newEvalError(interaction.Error)
+		if evalErr.Kind == EvalErrorTimeout {
+			return nil, &TimeoutError{Timeout: e.options.Timeout, Err: evalErr}
+		}
+		return nil, evalErr
+	}
+	return interaction.Result, nil
+}
+
+// next returns the interaction to serve for req.
+func (e *replayEvaluator) next(req EvaluateRequest) (*Interaction, error) {
+	key := replayKey{req.Source.Uri.String(), req.Source.Contents, req.Expr}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	recorded := e.interactions[key]
+	if len(recorded) == 0 {
+		return nil, &ReplayMismatchError{ModuleUri: key.moduleUri, Expr: key.expr, Reason: e.describeMissing(key)}
+	}
+	idx := min(e.served[key], len(recorded)-1)
+	e.served[key]++
+	return recorded[idx], nil
+}
+
+// describeMissing explains why no interaction was recorded for key.
+//
+// Callers must hold e.mu.
+func (e *replayEvaluator) describeMissing(key replayKey) string {
+	for other := range e.interactions {
+		if other.moduleUri == key.moduleUri && other.expr == key.expr {
+			return "module text differs from the recording"
+		}
+	}
+	return "request was not recorded"
+}
+
+// verifyRead performs read against the configured readers, and returns why the result differs from
+// the recording, if it does.
+func (e *replayEvaluator) verifyRead(read RecordedRead) string {
+	u, err := url.Parse(read.Uri)
+	if err != nil {
+		return fmt.Sprintf("recorded read has invalid uri %s", read.Uri)
+	}
+	var reader Reader
+	switch read.Operation {
+	case ReadResource, ListResources:
+		for _, r := range e.options.ResourceReaders {
+			if r.Scheme() == u.Scheme {
+				reader = r
+				break
+			}
+		}
+	default:
+		for _, r := range e.options.ModuleReaders {
+			if r.Scheme() == u.Scheme {
+				reader = r
+				break
+			}
+		}
+	}
+	if reader == nil {
+		return ""
+	}
+	var resp ReaderResponse
+	switch read.Operation {
+	case ReadResource:
+		contents, err := reader.(ResourceReader).Read(*u)
+		resp = ReaderResponse{Contents: contents, Err: err}
+	case ReadModule:
+		contents, err := reader.(ModuleReader).Read(*u)
+		resp = ReaderResponse{Contents: []byte(contents), Err: err}
+	default:
+		pathElements, err := reader.ListElements(*u)
+		resp = ReaderResponse{PathElements: pathElements, Err: err}
+	}
+	switch {
+	case read.NotFound != (resp.Err == ResourceNotFound):
+		return fmt.Sprintf("existence of %s changed since the recording", read.Uri)
+	case read.NotFound:
+		return ""
+	case read.Error != "" && resp.Err == nil, read.Error == "" && resp.Err != nil:
+		return fmt.Sprintf("%s of %s changed whether it fails since the recording", read.Operation, read.Uri)
+	case !bytes.Equal(read.Contents, resp.Contents):
+		return fmt.Sprintf("contents of %s changed since the recording", read.Uri)
+	case !slices.Equal(read.PathElements, toRecordedPathElements(resp.PathElements)):
+		return fmt.Sprintf("listing of %s changed since the recording", read.Uri)
+	}
+	return ""
+}
+
+func (e *replayEvaluator) log(msg LogMessage) {
+	switch msg.Level {
+	case LogLevelTrace:
+		e.options.Logger.Trace(msg.Message, msg.FrameUri)
+	default:
+		e.options.Logger.Warn(msg.Message, msg.FrameUri)
+	}
+}
+
+// recordingEvaluator is an evaluator whose recorded traffic gets saved when it is closed.
+type recordingEvaluator struct {
+	Evaluator
+	recorder *Recorder
+	path     string
+}
+
+func (e *recordingEvaluator) Close() error {
+	if err := e.Evaluator.Close(); err != nil {
+		return err
+	}
+	return e.recorder.Save(e.path)
+}
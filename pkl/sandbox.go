@@ -0,0 +1,254 @@
+//===----------------------------------------------------------------------===//
+// Copyright © 2026 Apple Inc. and the Pkl project authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//===----------------------------------------------------------------------===//
+
+package pkl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"regexp"
+	"slices"
+	"time"
+)
+
+// SandboxOptions configures WithSandbox.
+type SandboxOptions struct {
+	// FS holds the modules and resources that sandboxed Pkl code may read.
+	//
+	// If nil, only the Pkl standard library can be imported.
+	FS fs.FS
+
+	// Scheme is the URI scheme that FS is read through.
+	//
+	// If empty, defaults to "sandbox".
+	Scheme string
+
+	// RootDir is the directory that Pkl confines file access to.
+	// Files cannot be read in a sandbox anyway; RootDir only guards against mistakes.
+	//
+	// If empty, defaults to os.TempDir().
+	RootDir string
+
+	// Timeout is the maximum duration of a single evaluation.
+	//
+	// If zero, defaults to 10 seconds.
+	Timeout time.Duration
+
+	// MaxOutputBytes is the maximum size of the result of an evaluation, in bytes of the encoded
+	// result.
+	// Evaluations whose result is larger fail with ErrOutputTooLarge.
+	//
+	// If zero, defaults to 1 MiB.
+	MaxOutputBytes int
+}
+
+// SandboxReport describes the restrictions of an evaluator created with WithSandbox.
+type SandboxReport struct {
+	// AllowedModules are the patterns of the modules that may be imported.
+	//
+	// Like every evaluator, the evaluator also allows `repl:text`, the module that holds evaluated
+	// expressions.
+	AllowedModules []string
+
+	// AllowedResources are the patterns of the resources that may be read.
+	AllowedResources []string
+
+	// RootDir is the directory that file access is confined to.
+	RootDir string
+
+	// Timeout is the maximum duration of a single evaluation.
+	Timeout time.Duration
+
+	// MaxOutputBytes is the maximum size of the result of an evaluation.
+	MaxOutputBytes int
+}
+
+const (
+	defaultSandboxScheme         = "sandbox"
+	defaultSandboxTimeout        = 10 * time.Second
+	defaultSandboxMaxOutputBytes = 1 << 20
+)
+
+// sandboxState is what WithSandbox set up, for checking that later options do not loosen it.
+type sandboxState struct {
+	report          SandboxReport
+	moduleReaders   []ModuleReader
+	resourceReaders []ResourceReader
+}
+
+// ErrOutputTooLarge is returned by evaluations whose result exceeds SandboxOptions.MaxOutputBytes.
+var ErrOutputTooLarge = errors.New("output exceeds the size limit")
+
+// WithSandbox configures the evaluator to run untrusted Pkl code.
+//
+// Sandboxed code may only import the Pkl standard library, and import and read the files of
+// sandbox.FS through sandbox.Scheme.
+// It cannot read files, environment variables or external properties, and cannot reach the
+// network, neither through HTTP nor through packages.
+// Evaluations are bound by a timeout, and by a maximum size of their result.
+//
+// The returned report lists the effective restrictions.
+//
+// Options that come after WithSandbox may only tighten it, for example by removing allowed
+// patterns or readers, or by shortening the timeout: NewEvaluator fails if they loosen any of the
+// restrictions.
+//
+// WithSandbox drops EvaluatorOptions.EvaluationCache, so that sandboxes never share cached
+// results.
+// A cache that is set by a later option must use a namespace per sandbox; see
+// EvaluatorOptions.EvaluationCacheNamespace.
+//
+//goland:noinspection GoUnusedGlobalVariable
+var WithSandbox = func(sandbox SandboxOptions) (func(opts *EvaluatorOptions), SandboxReport) {
+	scheme := sandbox.Scheme
+	if scheme == "" {
+		scheme = defaultSandboxScheme
+	}
+	report := SandboxReport{
+		AllowedModules:   []string{"pkl:"},
+		AllowedResources: []string{},
+		RootDir:          sandbox.RootDir,
+		Timeout:          sandbox.Timeout,
+		MaxOutputBytes:   sandbox.MaxOutputBytes,
+	}
+	if sandbox.FS != nil {
+		report.AllowedModules = append(report.AllowedModules, regexp.QuoteMeta(scheme+":"))
+		report.AllowedResources = append(report.AllowedResources, regexp.QuoteMeta(scheme+":"))
+	}
+	if report.RootDir == "" {
+		report.RootDir = os.TempDir()
+	}
+	if report.Timeout <= 0 {
+		report.Timeout = defaultSandboxTimeout
+	}
+	if report.MaxOutputBytes <= 0 {
+		report.MaxOutputBytes = defaultSandboxMaxOutputBytes
+	}
+	opt := func(opts *EvaluatorOptions) {
+		state := &sandboxState{report: report}
+		if sandbox.FS != nil {
+			reader := &fsReader{fs: sandbox.FS, scheme: scheme}
+			state.moduleReaders = []ModuleReader{&fsModuleReader{reader}}
+			state.resourceReaders = []ResourceReader{&fsResourceReader{reader}}
+		}
+		// only keep the options that cannot widen what Pkl code can access.
+		*opts = EvaluatorOptions{
+			Logger:             opts.Logger,
+			OutputFormat:       opts.OutputFormat,
+			TraceMode:          opts.TraceMode,
+			Interceptors:       opts.Interceptors,
+			MetricsRecorders:   opts.MetricsRecorders,
+			RequiredPklVersion: opts.RequiredPklVersion,
+			errs:               opts.errs,
+			AllowedModules:     slices.Clone(report.AllowedModules),
+			AllowedResources:   slices.Clone(report.AllowedResources),
+			ModuleReaders:      slices.Clone(state.moduleReaders),
+			ResourceReaders:    slices.Clone(state.resourceReaders),
+			RootDir:            report.RootDir,
+			Timeout:            report.Timeout,
+			sandbox:            state,
+		}
+	}
+	// the returned report must not share slices with the options.
+	returned := report
+	returned.AllowedModules = slices.Clone(report.AllowedModules)
+	returned.AllowedResources = slices.Clone(report.AllowedResources)
+	return opt, returned
+}
+
+// limitOutput is the interceptor that enforces MaxOutputBytes.
+//
+// It is added by NewEvaluator after all options are applied, so that no option can remove it.
+func (s *sandboxState) limitOutput() Interceptor {
+	maxOutputBytes := s.report.MaxOutputBytes
+	return Interceptor{
+		Evaluate: func(ctx context.Context, req EvaluateRequest, next EvaluateHandler) ([]byte, error) {
+			out, err := next(ctx, req)
+			if err == nil && len(out) > maxOutputBytes {
+				return nil, fmt.Errorf("%w: the result has %d bytes, but at most %d bytes are allowed", ErrOutputTooLarge, len(out), maxOutputBytes)
+			}
+			return out, err
+		},
+	}
+}
+
+// checkSandbox checks that options applied after WithSandbox did not loosen the sandbox.
+func (e *EvaluatorOptions) checkSandbox() error {
+	if e.sandbox == nil {
+		return nil
+	}
+	report := e.sandbox.report
+	var errs []error
+	loosened := func(option string) {
+		errs = append(errs, fmt.Errorf("EvaluatorOptions.%s was loosened after WithSandbox", option))
+	}
+	if !isSubset(e.AllowedModules, report.AllowedModules) {
+		loosened("AllowedModules")
+	}
+	if !isSubset(e.AllowedResources, report.AllowedResources) {
+		loosened("AllowedResources")
+	}
+	if len(e.Env) > 0 {
+		loosened("Env")
+	}
+	if len(e.Properties) > 0 {
+		loosened("Properties")
+	}
+	if e.RootDir != report.RootDir {
+		loosened("RootDir")
+	}
+	if e.Timeout <= 0 || e.Timeout > report.Timeout {
+		loosened("Timeout")
+	}
+	if e.CacheDir != "" {
+		loosened("CacheDir")
+	}
+	if len(e.ModulePaths) > 0 {
+		loosened("ModulePaths")
+	}
+	if e.ProjectBaseURI != "" || e.DeclaredProjectDependencies != nil {
+		loosened("ProjectBaseURI")
+	}
+	if e.Http != nil {
+		loosened("Http")
+	}
+	if len(e.ExternalModuleReaders) > 0 {
+		loosened("ExternalModuleReaders")
+	}
+	if len(e.ExternalResourceReaders) > 0 {
+		loosened("ExternalResourceReaders")
+	}
+	if !isSubset(e.ModuleReaders, e.sandbox.moduleReaders) {
+		loosened("ModuleReaders")
+	}
+	if !isSubset(e.ResourceReaders, e.sandbox.resourceReaders) {
+		loosened("ResourceReaders")
+	}
+	return errors.Join(errs...)
+}
+
+// isSubset tells if every element of elements is also an element of of.
+func isSubset[T comparable](elements, of []T) bool {
+	for _, element := range elements {
+		if !slices.Contains(of, element) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,139 @@
+//===----------------------------------------------------------------------===//
+// Copyright © 2026 Apple Inc. and the Pkl project authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//===----------------------------------------------------------------------===//
+
+package pkl_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/apple/pkl-go/pkl"
+	"github.com/apple/pkl-go/pkl/pkltest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithSandbox(t *testing.T) {
+	server := pkltest.NewServer()
+	manager := newServerManager(t, server)
+
+	sandbox, report := pkl.WithSandbox(pkl.SandboxOptions{
+		FS:     fstest.MapFS{"config.pkl": {Data: []byte("foo = 1")}},
+		Scheme: "tenant",
+	})
+	assert.Equal(t, pkl.SandboxReport{
+		AllowedModules:   []string{"pkl:", "tenant:"},
+		AllowedResources: []string{"tenant:"},
+		RootDir:          os.TempDir(),
+		Timeout:          10 * time.Second,
+		MaxOutputBytes:   1 << 20,
+	}, report)
+	// changing the report does not change the sandbox.
+	report.AllowedModules[0] = "file:"
+
+	_, err := manager.NewEvaluator(context.Background(), pkl.PreconfiguredOptions, func(opts *pkl.EvaluatorOptions) { opts.Properties = map[string]string{"secret": "s3cr3t"} }, sandbox, pkl.WithTimeout(time.Second))
+	if !assert.NoError(t, err) {
+		return
+	}
+	evaluators := server.Evaluators()
+	if !assert.Len(t, evaluators, 1) {
+		return
+	}
+	req := evaluators[0]
+	assert.Equal(t, []string{"pkl:", "tenant:", "repl:text"}, req.AllowedModules)
+	assert.Equal(t, []string{"tenant:"}, req.AllowedResources)
+	assert.Empty(t, req.Env)
+	assert.Empty(t, req.Properties)
+	assert.Empty(t, req.CacheDir)
+	assert.Equal(t, os.TempDir(), req.RootDir)
+	assert.Equal(t, int64(1), req.TimeoutSeconds)
+	assert.Equal(t, []string{"tenant"}, req.ResourceReaderSchemes)
+}
+
+func TestWithSandbox_tightened(t *testing.T) {
+	server := pkltest.NewServer()
+	server.HandleEvaluate(func(call *pkltest.Call) ([]byte, error) {
+		return pkltest.Encode(nil)
+	})
+	manager := newServerManager(t, server)
+
+	cache, err := pkl.NewEvaluationCache(pkl.EvaluationCacheOptions{})
+	if !assert.NoError(t, err) {
+		return
+	}
+	sandbox, _ := pkl.WithSandbox(pkl.SandboxOptions{FS: fstest.MapFS{}})
+	ev, err := manager.NewEvaluator(context.Background(), pkl.WithEvaluationCache(cache), sandbox, func(opts *pkl.EvaluatorOptions) {
+		opts.AllowedModules = []string{"pkl:"}
+		opts.AllowedResources = nil
+		opts.ResourceReaders = nil
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	evaluators := server.Evaluators()
+	if !assert.Len(t, evaluators, 1) {
+		return
+	}
+	req := evaluators[0]
+	assert.Equal(t, []string{"pkl:", "repl:text"}, req.AllowedModules)
+	assert.Empty(t, req.AllowedResources)
+	assert.Empty(t, req.ResourceReaderSchemes)
+	// sandboxes do not share cached results.
+	for range 2 {
+		_, err = ev.EvaluateExpressionRaw(context.Background(), pkl.TextSource("foo = 1"), "foo")
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 2, server.Evaluations())
+}
+
+func TestWithSandbox_loosened(t *testing.T) {
+	manager := newServerManager(t, pkltest.NewServer())
+
+	sandbox, _ := pkl.WithSandbox(pkl.SandboxOptions{})
+	_, err := manager.NewEvaluator(context.Background(), sandbox, pkl.WithOsEnv, pkl.WithDefaultAllowedResources, pkl.WithTimeout(time.Minute))
+	assert.EqualError(t, err, "EvaluatorOptions.AllowedResources was loosened after WithSandbox\n"+
+		"EvaluatorOptions.Env was loosened after WithSandbox\n"+
+		"EvaluatorOptions.Timeout was loosened after WithSandbox")
+
+	_, err = manager.NewEvaluator(context.Background(), sandbox, pkl.WithResourceReader(&changingResourceReader{}))
+	assert.ErrorContains(t, err, "EvaluatorOptions.ResourceReaders was loosened after WithSandbox")
+}
+
+func TestWithSandbox_MaxOutputBytes(t *testing.T) {
+	server := pkltest.NewServer()
+	server.HandleEvaluate(func(call *pkltest.Call) ([]byte, error) {
+		if call.Expr == "large" {
+			return []byte{1, 2, 3}, nil
+		}
+		return []byte{1, 2}, nil
+	})
+	manager := newServerManager(t, server)
+
+	sandbox, _ := pkl.WithSandbox(pkl.SandboxOptions{FS: fstest.MapFS{}, MaxOutputBytes: 2})
+	ev, err := manager.NewEvaluator(context.Background(), sandbox)
+	if !assert.NoError(t, err) {
+		return
+	}
+	out, err := ev.EvaluateExpressionRaw(context.Background(), pkl.TextSource("foo = 1"), "foo")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{1, 2}, out)
+	out, err = ev.EvaluateExpressionRaw(context.Background(), pkl.TextSource("foo = 1"), "large")
+	assert.Nil(t, out)
+	assert.ErrorIs(t, err, pkl.ErrOutputTooLarge)
+	assert.EqualError(t, err, "output exceeds the size limit: the result has 3 bytes, but at most 2 bytes are allowed")
+}
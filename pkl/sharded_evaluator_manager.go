@@ -0,0 +1,235 @@
+//===----------------------------------------------------------------------===//
+// Copyright © 2026 Apple Inc. and the Pkl project authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//===----------------------------------------------------------------------===//
+
+package pkl
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"runtime"
+	"slices"
+	"sync"
+
+	"github.com/apple/pkl-go/pkl/internal"
+)
+
+// ShardStrategy decides which Pkl process of a sharded EvaluatorManager a new evaluator is
+// created in.
+type ShardStrategy int
+
+const (
+	// ShardRoundRobin creates evaluators in each process in turn.
+	ShardRoundRobin ShardStrategy = iota
+
+	// ShardLeastLoaded creates evaluators in the process with the fewest in-flight evaluations,
+	// and then the fewest open evaluators.
+	ShardLeastLoaded
+)
+
+// ShardingOptions configures an EvaluatorManager that runs several Pkl processes, and spreads its
+// evaluators across them.
+//
+// An evaluator stays in the process it was created in.
+// When a process exits, its evaluators fail like those of an unsharded manager, and the process is
+// replaced by a spare for the evaluators created afterwards.
+// If the manager has SupervisorOptions, each process is supervised on its own, and is only
+// replaced once its supervisor gives up.
+type ShardingOptions struct {
+	// Processes is the number of Pkl processes that evaluators are spread across.
+	//
+	// If zero, defaults to the number of CPUs.
+	Processes int
+
+	// Strategy decides which process a new evaluator is created in.
+	Strategy ShardStrategy
+
+	// Spares is the number of Pkl processes kept running ahead of time, to replace processes that
+	// exit without waiting for Pkl to start.
+	//
+	// Spares are started after the first evaluator is created.
+	Spares int
+}
+
+// WithSharding spreads the evaluators of the EvaluatorManager across several Pkl processes.
+var WithSharding = func(sharding ShardingOptions) func(opts *EvaluatorManagerOptions) {
+	return func(opts *EvaluatorManagerOptions) {
+		opts.Sharding = &sharding
+	}
+}
+
+// shardedEvaluatorManager is an EvaluatorManager that creates its evaluators in one of several
+// evaluator managers, each backed by its own Pkl process.
+type shardedEvaluatorManager struct {
+	options ShardingOptions
+	// newShard creates an evaluator manager for a new Pkl process.
+	newShard func() *evaluatorManager
+	stats    statsCollector
+
+	// mu guards shards, spares, next and closed.
+	mu     sync.Mutex
+	shards []*evaluatorManager
+	spares []*evaluatorManager
+	// next is the index of the shard that the next evaluator is created in, when round-robin.
+	next   int
+	closed bool
+}
+
+var _ EvaluatorManager = (*shardedEvaluatorManager)(nil)
+
+func newShardedEvaluatorManager(newImpl func() evaluatorManagerImpl, o EvaluatorManagerOptions) *shardedEvaluatorManager {
+	sharding := *o.Sharding
+	if sharding.Processes <= 0 {
+		sharding.Processes = runtime.NumCPU()
+	}
+	s := &shardedEvaluatorManager{options: sharding}
+	o.MetricsRecorders = append(slices.Clone(o.MetricsRecorders), &s.stats)
+	s.newShard = func() *evaluatorManager {
+		return newEvaluatorManager(newImpl(), o)
+	}
+	s.shards = make([]*evaluatorManager, sharding.Processes)
+	for i := range s.shards {
+		s.shards[i] = s.newShard()
+	}
+	return s
+}
+
+func (s *shardedEvaluatorManager) NewEvaluator(ctx context.Context, opts ...func(options *EvaluatorOptions)) (Evaluator, error) {
+	shard, err := s.pick()
+	if err != nil {
+		return nil, err
+	}
+	return shard.NewEvaluator(ctx, opts...)
+}
+
+func (s *shardedEvaluatorManager) NewProjectEvaluator(ctx context.Context, projectBaseUrl *url.URL, opts ...func(options *EvaluatorOptions)) (Evaluator, error) {
+	shard, err := s.pick()
+	if err != nil {
+		return nil, err
+	}
+	return shard.NewProjectEvaluator(ctx, projectBaseUrl, opts...)
+}
+
+// pick returns the shard that a new evaluator is created in.
+//
+// Shards whose Pkl process has exited are replaced first, and spares are topped up.
+func (s *shardedEvaluatorManager) pick() (*evaluatorManager, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil, ErrManagerClosed
+	}
+	for i, shard := range s.shards {
+		if shard.closed.get() {
+			s.shards[i] = s.takeSpare()
+		}
+	}
+	s.fillSpares()
+	if s.options.Strategy == ShardLeastLoaded {
+		best, bestActive, bestEvaluators := 0, 0, 0
+		for i, shard := range s.shards {
+			active, evaluators := shard.load()
+			if i == 0 || active < bestActive || (active == bestActive && evaluators < bestEvaluators) {
+				best, bestActive, bestEvaluators = i, active, evaluators
+			}
+		}
+		return s.shards[best], nil
+	}
+	shard := s.shards[s.next]
+	s.next = (s.next + 1) % len(s.shards)
+	return shard, nil
+}
+
+// takeSpare returns a spare that is still running, or a new shard if there is none.
+func (s *shardedEvaluatorManager) takeSpare() *evaluatorManager {
+	for len(s.spares) > 0 {
+		spare := s.spares[0]
+		s.spares = s.spares[1:]
+		if !spare.closed.get() {
+			return spare
+		}
+	}
+	return s.newShard()
+}
+
+// fillSpares starts spares until there are ShardingOptions.Spares of them.
+func (s *shardedEvaluatorManager) fillSpares() {
+	s.spares = slices.DeleteFunc(s.spares, func(spare *evaluatorManager) bool {
+		return spare.closed.get()
+	})
+	for len(s.spares) < s.options.Spares {
+		spare := s.newShard()
+		s.spares = append(s.spares, spare)
+		go func() {
+			if err := spare.warmUp(); err != nil {
+				internal.Debug("Failed to start spare Pkl process: %v", err)
+			}
+		}()
+	}
+}
+
+// stop stops accepting new evaluators, and returns every shard and spare.
+func (s *shardedEvaluatorManager) stop() []*evaluatorManager {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	return append(slices.Clone(s.shards), s.spares...)
+}
+
+func (s *shardedEvaluatorManager) Close() error {
+	var errs []error
+	for _, m := range s.stop() {
+		errs = append(errs, m.Close())
+	}
+	return errors.Join(errs...)
+}
+
+func (s *shardedEvaluatorManager) Shutdown(ctx context.Context) error {
+	managers := s.stop()
+	errs := make([]error, len(managers))
+	var wg sync.WaitGroup
+	for i, m := range managers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = m.Shutdown(ctx)
+		}()
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return errors.Join(errs...)
+}
+
+// anyShard returns a shard to answer questions about Pkl, which are the same for every shard.
+func (s *shardedEvaluatorManager) anyShard() *evaluatorManager {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.shards[0]
+}
+
+func (s *shardedEvaluatorManager) GetVersion() (string, error) {
+	return s.anyShard().GetVersion()
+}
+
+func (s *shardedEvaluatorManager) Capabilities() (*Capabilities, error) {
+	return s.anyShard().Capabilities()
+}
+
+func (s *shardedEvaluatorManager) Stats() Stats {
+	return s.stats.snapshot()
+}
@@ -0,0 +1,104 @@
+//===----------------------------------------------------------------------===//
+// Copyright © 2026 Apple Inc. and the Pkl project authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//===----------------------------------------------------------------------===//
+
+package pkl_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/apple/pkl-go/pkl"
+	"github.com/stretchr/testify/assert"
+)
+
+// pidOf returns the ID of the fake Pkl process that ev was created in.
+func pidOf(t *testing.T, ev pkl.Evaluator) int {
+	var pid int
+	if err := ev.EvaluateExpression(context.Background(), pkl.TextSource(""), "pid", &pid); err != nil {
+		t.Fatal(err)
+	}
+	return pid
+}
+
+// newShardedEvaluators creates n evaluators with manager, and returns them with the IDs of the
+// processes they were created in.
+func newShardedEvaluators(t *testing.T, manager pkl.EvaluatorManager, n int) ([]pkl.Evaluator, []int) {
+	var evaluators []pkl.Evaluator
+	var pids []int
+	for range n {
+		ev, err := manager.NewEvaluator(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		evaluators = append(evaluators, ev)
+		pids = append(pids, pidOf(t, ev))
+	}
+	return evaluators, pids
+}
+
+func TestShardedEvaluatorManager_RoundRobin(t *testing.T) {
+	manager := newFakePklManager(pkl.WithSharding(pkl.ShardingOptions{Processes: 3}))
+	defer func() { assert.NoError(t, manager.Close()) }()
+
+	_, pids := newShardedEvaluators(t, manager, 4)
+	assert.Equal(t, pids[0], pids[3])
+	assert.NotEqual(t, pids[0], pids[1])
+	assert.NotEqual(t, pids[0], pids[2])
+	assert.NotEqual(t, pids[1], pids[2])
+}
+
+func TestShardedEvaluatorManager_LeastLoaded(t *testing.T) {
+	manager := newFakePklManager(pkl.WithSharding(pkl.ShardingOptions{Processes: 2, Strategy: pkl.ShardLeastLoaded}))
+	defer func() { assert.NoError(t, manager.Close()) }()
+
+	evaluators, pids := newShardedEvaluators(t, manager, 2)
+	assert.NotEqual(t, pids[0], pids[1])
+
+	assert.NoError(t, evaluators[0].Close())
+	_, next := newShardedEvaluators(t, manager, 1)
+	assert.Equal(t, pids[0], next[0])
+}
+
+func TestShardedEvaluatorManager_Spares(t *testing.T) {
+	manager := newFakePklManager(pkl.WithSharding(pkl.ShardingOptions{Processes: 2, Spares: 1}))
+	defer func() { assert.NoError(t, manager.Close()) }()
+
+	evaluators, pids := newShardedEvaluators(t, manager, 1)
+
+	// the Pkl process of the first shard exits.
+	_, err := evaluators[0].EvaluateExpressionRaw(context.Background(), pkl.TextSource(""), "exit:0")
+	assert.Error(t, err)
+	assert.Eventually(t, evaluators[0].Closed, time.Second, 10*time.Millisecond)
+
+	// the second evaluator goes to the second shard, and the third to the spare that replaced the
+	// first.
+	_, next := newShardedEvaluators(t, manager, 2)
+	assert.NotEqual(t, pids[0], next[0])
+	assert.NotContains(t, []int{pids[0], next[0]}, next[1])
+}
+
+func TestShardedEvaluatorManager_Close(t *testing.T) {
+	manager := newFakePklManager(pkl.WithSharding(pkl.ShardingOptions{Processes: 2, Spares: 1}))
+	ev, err := manager.NewEvaluator(context.Background())
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.NoError(t, manager.Shutdown(context.Background()))
+	assert.True(t, ev.Closed())
+	_, err = manager.NewEvaluator(context.Background())
+	assert.ErrorIs(t, err, pkl.ErrManagerClosed)
+}
@@ -0,0 +1,101 @@
+//===----------------------------------------------------------------------===//
+// Copyright © 2026 Apple Inc. and the Pkl project authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//===----------------------------------------------------------------------===//
+
+package pkl
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/apple/pkl-go/pkl/internal"
+)
+
+// SocketOptions configures an EvaluatorManager that connects to an already running Pkl server
+// over a socket, instead of spawning its own `pkl server` process.
+//
+// The server must speak the same message passing API as `pkl server` does over standard input and
+// output, and must serve each connection as an independent session.
+//
+// A lost connection is re-established according to the manager's SupervisorOptions.
+// If the manager has none, it reconnects with default SupervisorOptions, and closes after 10
+// consecutive failed attempts.
+type SocketOptions struct {
+	// Network is the network of the socket, such as "unix" or "tcp".
+	Network string
+
+	// Address is the address of the socket, such as "/var/run/pkl.sock" or "localhost:4040".
+	Address string
+
+	// DialTimeout bounds how long it may take to connect to the server.
+	//
+	// If zero, defaults to 10 seconds.
+	DialTimeout time.Duration
+
+	// PklVersion is the version of Pkl that the server runs.
+	//
+	// Unlike a spawned process, a server cannot be asked for its version, so it must be set.
+	// It decides which features pkl-go uses, and is checked against version requirements such as
+	// EvaluatorOptions.RequiredPklVersion.
+	PklVersion string
+}
+
+// WithSocket connects the EvaluatorManager to an already running Pkl server over a socket.
+var WithSocket = func(socket SocketOptions) func(opts *EvaluatorManagerOptions) {
+	return func(opts *EvaluatorManagerOptions) {
+		opts.Socket = &socket
+	}
+}
+
+// NewSocketTransport creates a Transport that connects to an already running Pkl server over a
+// socket.
+//
+// Unlike WithSocket, the resulting manager does not reconnect unless it is given SupervisorOptions.
+func NewSocketTransport(socket SocketOptions) Transport {
+	return &socketTransport{options: socket}
+}
+
+// defaultSocketSupervisor is how a socket connection is re-established if the manager has no
+// SupervisorOptions.
+var defaultSocketSupervisor = SupervisorOptions{MaxRestarts: 10}
+
+type socketTransport struct {
+	options SocketOptions
+}
+
+var _ Transport = (*socketTransport)(nil)
+
+func (s *socketTransport) Connect() (io.ReadWriteCloser, error) {
+	timeout := s.options.DialTimeout
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+	internal.Debug("Connecting to Pkl server at %s %s", s.options.Network, s.options.Address)
+	conn, err := net.DialTimeout(s.options.Network, s.options.Address, timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to Pkl server at %s: %w", s.options.Address, err)
+	}
+	return conn, nil
+}
+
+func (s *socketTransport) PklVersion() (string, error) {
+	if s.options.PklVersion == "" {
+		return "", errors.New("SocketOptions.PklVersion must be set to the version of Pkl that the server runs")
+	}
+	return s.options.PklVersion, nil
+}
@@ -0,0 +1,82 @@
+//===----------------------------------------------------------------------===//
+// Copyright © 2026 Apple Inc. and the Pkl project authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//===----------------------------------------------------------------------===//
+
+package pkl_test
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/apple/pkl-go/pkl"
+	"github.com/apple/pkl-go/pkl/pkltest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvaluatorManager_Socket(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer listener.Close()
+	server := pkltest.NewServer()
+	defer server.Close()
+	server.HandleEvaluate(func(call *pkltest.Call) ([]byte, error) {
+		return pkltest.Encode(call.Expr)
+	})
+	go func() { assert.NoError(t, server.Serve(listener)) }()
+
+	restarts := make(chan pkl.RestartEvent, 1)
+	manager := pkl.NewEvaluatorManagerWithOptions(
+		pkl.WithSocket(pkl.SocketOptions{Network: "tcp", Address: listener.Addr().String(), PklVersion: "0.30.0"}),
+		pkl.WithSupervisor(pkl.SupervisorOptions{OnRestart: func(event pkl.RestartEvent) { restarts <- event }}),
+	)
+	defer func() { assert.NoError(t, manager.Close()) }()
+	version, err := manager.GetVersion()
+	assert.NoError(t, err)
+	assert.Equal(t, "0.30.0", version)
+
+	ev, err := manager.NewEvaluator(context.Background())
+	if !assert.NoError(t, err) {
+		return
+	}
+	out, err := ev.EvaluateExpressionRaw(context.Background(), pkl.TextSource("foo = 1"), "foo")
+	assert.NoError(t, err)
+	assert.Equal(t, pkltest.MustEncode("foo"), out)
+
+	// the server drops the connection, so the manager reconnects.
+	server.Crash()
+	event := <-restarts
+	assert.NoError(t, event.Err)
+	assert.Equal(t, 2, server.Sessions())
+	out, err = ev.EvaluateExpressionRaw(context.Background(), pkl.TextSource("foo = 1"), "foo")
+	assert.NoError(t, err)
+	assert.Equal(t, pkltest.MustEncode("foo"), out)
+}
+
+func TestEvaluatorManager_Socket_unreachable(t *testing.T) {
+	manager := pkl.NewEvaluatorManagerWithOptions(pkl.WithSocket(pkl.SocketOptions{Network: "unix", Address: t.TempDir() + "/missing.sock", PklVersion: "0.30.0"}))
+	defer func() { assert.NoError(t, manager.Close()) }()
+	_, err := manager.NewEvaluator(context.Background())
+	assert.ErrorContains(t, err, "failed to connect to Pkl server")
+}
+
+func TestEvaluatorManager_Socket_missingVersion(t *testing.T) {
+	manager := pkl.NewEvaluatorManagerWithOptions(pkl.WithSocket(pkl.SocketOptions{Network: "unix", Address: t.TempDir() + "/pkl.sock"}))
+	defer func() { assert.NoError(t, manager.Close()) }()
+	_, err := manager.GetVersion()
+	assert.EqualError(t, err, "SocketOptions.PklVersion must be set to the version of Pkl that the server runs")
+}
@@ -0,0 +1,149 @@
+//===----------------------------------------------------------------------===//
+// Copyright © 2026 Apple Inc. and the Pkl project authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//===----------------------------------------------------------------------===//
+
+package pkl
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/apple/pkl-go/pkl/internal"
+)
+
+// SupervisorOptions controls how a supervised EvaluatorManager restarts its Pkl process.
+//
+// When the Pkl process exits unexpectedly, the manager starts a new process, recreates every open
+// evaluator with its original EvaluatorOptions, and resends the evaluations that were in flight.
+// Callers that are waiting on those evaluations do not observe the restart.
+type SupervisorOptions struct {
+	// MaxRestarts is the number of consecutive failed restart attempts after which the manager
+	// gives up and closes.
+	//
+	// If zero, the manager keeps trying to restart the process.
+	MaxRestarts int
+
+	// InitialBackoff is how long to wait before the first restart attempt.
+	// The wait doubles after every failed attempt, up to MaxBackoff.
+	//
+	// If zero, defaults to 100 milliseconds.
+	InitialBackoff time.Duration
+
+	// MaxBackoff is the longest wait between two restart attempts.
+	//
+	// If zero, defaults to 30 seconds.
+	MaxBackoff time.Duration
+
+	// Timeout bounds how long a single restart attempt, including recreating evaluators, may take.
+	//
+	// If zero, defaults to 30 seconds.
+	Timeout time.Duration
+
+	// OnRestart, if set, is called after every restart attempt.
+	OnRestart func(event RestartEvent)
+}
+
+// RestartEvent describes an attempt to restart the Pkl process of a supervised EvaluatorManager.
+type RestartEvent struct {
+	// Attempt is the number of this attempt, starting from 1, since the process last exited.
+	Attempt int
+
+	// Cause is the reason that the process exited.
+	Cause error
+
+	// Err is the error that caused this attempt to fail, or nil if the process was restarted.
+	Err error
+}
+
+// WithSupervisor restarts the Pkl process if it exits unexpectedly, instead of closing the
+// EvaluatorManager.
+var WithSupervisor = func(supervisor SupervisorOptions) func(opts *EvaluatorManagerOptions) {
+	return func(opts *EvaluatorManagerOptions) {
+		opts.Supervisor = &supervisor
+	}
+}
+
+// restartableImpl is implemented by an evaluatorManagerImpl that can replace its Pkl process.
+type restartableImpl interface {
+	// restart stops the current Pkl process if it is still running, and starts a new one.
+	restart() error
+}
+
+// restart restarts the Pkl process after it exited with cause, retrying with backoff until it
+// succeeds, or until MaxRestarts attempts fail.
+func (m *evaluatorManager) restart(cause error) error {
+	impl, ok := m.impl.(restartableImpl)
+	if !ok {
+		return cause
+	}
+	backoff := m.supervisor.InitialBackoff
+	if backoff == 0 {
+		backoff = 100 * time.Millisecond
+	}
+	maxBackoff := m.supervisor.MaxBackoff
+	if maxBackoff == 0 {
+		maxBackoff = 30 * time.Second
+	}
+	timeout := m.supervisor.Timeout
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+	for attempt := 1; ; attempt++ {
+		time.Sleep(backoff)
+		if m.closed.get() {
+			return nil
+		}
+		internal.Debug("Restarting Pkl (attempt %d) after it exited: %v", attempt, cause)
+		err := m.restartOnce(impl, timeout)
+		if m.supervisor.OnRestart != nil {
+			m.supervisor.OnRestart(RestartEvent{Attempt: attempt, Cause: cause, Err: err})
+		}
+		if err == nil {
+			return nil
+		}
+		if m.supervisor.MaxRestarts > 0 && attempt >= m.supervisor.MaxRestarts {
+			return fmt.Errorf("failed to restart Pkl after %d attempts: %w", attempt, err)
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
+
+func (m *evaluatorManager) restartOnce(impl restartableImpl, timeout time.Duration) error {
+	// from now on, responses to evaluations that were sent before the restart are ignored, and
+	// the evaluations are resent by recreate.
+	m.generation.Add(1)
+	if err := impl.restart(); err != nil {
+		return err
+	}
+	// requests to create evaluators that were sent to the previous process will never be answered.
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	m.pendingEvaluators.Range(func(_, value any) bool {
+		m.send(ctx, value.(*pendingEvaluator).msg)
+		return true
+	})
+	var evaluators []*evaluator
+	m.evaluators.Range(func(_, value any) bool {
+		evaluators = append(evaluators, value.(*evaluator))
+		return true
+	})
+	for _, ev := range evaluators {
+		if err := ev.recreate(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+//===----------------------------------------------------------------------===//
+// Copyright © 2026 Apple Inc. and the Pkl project authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//===----------------------------------------------------------------------===//
+
+package pkl_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/apple/pkl-go/pkl"
+	"github.com/apple/pkl-go/pkl/pkltest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSupervisor_restart(t *testing.T) {
+	server := pkltest.NewServer()
+	evaluations := make(chan int64, 2)
+	server.HandleEvaluate(func(call *pkltest.Call) ([]byte, error) {
+		evaluations <- call.EvaluatorId
+		if call.EvaluatorId == 1 {
+			<-call.Done()
+			return nil, errors.New("evaluator closed")
+		}
+		return pkltest.Encode("foo")
+	})
+	events := make(chan pkl.RestartEvent, 1)
+	manager := newServerManager(t, server, pkl.WithSupervisor(pkl.SupervisorOptions{
+		InitialBackoff: time.Millisecond,
+		OnRestart:      func(event pkl.RestartEvent) { events <- event },
+	}))
+
+	ev, err := manager.NewEvaluator(context.Background())
+	if !assert.NoError(t, err) {
+		return
+	}
+	type result struct {
+		out []byte
+		err error
+	}
+	results := make(chan result)
+	go func() {
+		out, err := ev.EvaluateExpressionRaw(context.Background(), pkl.TextSource("foo = 1"), "foo")
+		results <- result{out, err}
+	}()
+	assert.Equal(t, int64(1), <-evaluations)
+
+	server.Crash()
+	event := <-events
+	assert.Equal(t, 1, event.Attempt)
+	assert.Error(t, event.Cause)
+	assert.NoError(t, event.Err)
+
+	// the evaluator is recreated, and the in-flight evaluation is sent to it.
+	assert.Equal(t, int64(2), <-evaluations)
+	res := <-results
+	assert.NoError(t, res.err)
+	assert.Equal(t, pkltest.MustEncode("foo"), res.out)
+	assert.Equal(t, 2, server.Sessions())
+	assert.False(t, ev.Closed())
+}
+
+func TestSupervisor_MaxRestarts(t *testing.T) {
+	server := pkltest.NewServer()
+	events := make(chan pkl.RestartEvent, 10)
+	manager := newServerManager(t, server, pkl.WithSupervisor(pkl.SupervisorOptions{
+		MaxRestarts:    2,
+		InitialBackoff: time.Millisecond,
+		OnRestart:      func(event pkl.RestartEvent) { events <- event },
+	}))
+	if _, err := manager.NewEvaluator(context.Background()); !assert.NoError(t, err) {
+		return
+	}
+
+	// the server refuses new sessions, so every restart fails.
+	server.Close()
+	assert.Eventually(t, func() bool {
+		_, err := manager.NewEvaluator(context.Background())
+		return errors.Is(err, pkl.ErrManagerClosed)
+	}, time.Second, 10*time.Millisecond)
+	if assert.Len(t, events, 2) {
+		<-events
+		event := <-events
+		assert.Equal(t, 2, event.Attempt)
+		assert.EqualError(t, event.Err, "pkltest: server is closed")
+	}
+}
@@ -0,0 +1,283 @@
+//===----------------------------------------------------------------------===//
+// Copyright © 2026 Apple Inc. and the Pkl project authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//===----------------------------------------------------------------------===//
+
+package pkl
+
+import (
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/apple/pkl-go/pkl/internal"
+	"github.com/apple/pkl-go/pkl/internal/msgapi"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Transport connects an EvaluatorManager to Pkl.
+//
+// A Transport provides a byte stream that carries the messages of Pkl's message passing API, in
+// the same framing that `pkl server` uses over standard input and output.
+// The EvaluatorManager takes care of encoding and decoding messages, and of dispatching them to
+// evaluators and readers.
+//
+// Transports can be used to reach Pkl in ways that pkl-go does not support out of the box, for
+// example within a container, or through a recording of an earlier session.
+//
+// Backends that exchange whole messages rather than a byte stream, such as a binding of Pkl as a
+// native library, can implement MessageTransport instead.
+type Transport interface {
+	// Connect starts a new session with Pkl, and returns the stream that the session's messages
+	// are exchanged over.
+	//
+	// The EvaluatorManager closes the stream when it is closed.
+	// If reading from the stream fails, the session is considered lost. If the manager is
+	// supervised, Connect is called again to start a new session.
+	Connect() (io.ReadWriteCloser, error)
+
+	// PklVersion returns the version of Pkl on the other end of the transport.
+	PklVersion() (string, error)
+}
+
+// NewEvaluatorManagerWithTransport creates a new EvaluatorManager that talks to Pkl through the
+// given transport.
+//
+// Options that control how Pkl is spawned, such as WithPklCommand, have no effect.
+func NewEvaluatorManagerWithTransport(transport Transport, opts ...func(options *EvaluatorManagerOptions)) EvaluatorManager {
+	o := EvaluatorManagerOptions{}
+	for _, f := range opts {
+		f(&o)
+	}
+	return newEvaluatorManager(newTransportEvaluator(transport), o)
+}
+
+// transportEvaluator is the evaluatorManagerImpl that exchanges messages over a Transport.
+type transportEvaluator struct {
+	in     chan msgapi.IncomingMessage
+	out    chan msgapi.OutgoingMessage
+	closed chan error
+	// exited is a flag that indicates evaluator was closed explicitly
+	exited    atomicBool
+	transport Transport
+	// closing is closed once deinit starts.
+	closing chan struct{}
+	// reportMu is held for reading while reporting to closed, and for writing while closing it.
+	reportMu sync.RWMutex
+
+	// mu guards session and version.
+	mu      sync.Mutex
+	session *transportSession
+	version *internal.Semver
+}
+
+// transportSession is a single session with Pkl.
+type transportSession struct {
+	stream io.ReadWriteCloser
+	// done is closed once the session stopped reading messages.
+	done chan struct{}
+	// replaced is closed once the session has been replaced by a new one.
+	replaced    chan struct{}
+	replaceOnce sync.Once
+	lostOnce    sync.Once
+}
+
+func newTransportEvaluator(transport Transport) *transportEvaluator {
+	return &transportEvaluator{
+		in:        make(chan msgapi.IncomingMessage),
+		out:       make(chan msgapi.OutgoingMessage),
+		closed:    make(chan error),
+		transport: transport,
+		closing:   make(chan struct{}),
+	}
+}
+
+var (
+	_ evaluatorManagerImpl = (*transportEvaluator)(nil)
+	_ restartableImpl      = (*transportEvaluator)(nil)
+)
+
+func (t *transportEvaluator) inChan() chan msgapi.IncomingMessage {
+	return t.in
+}
+
+func (t *transportEvaluator) outChan() chan msgapi.OutgoingMessage {
+	return t.out
+}
+
+func (t *transportEvaluator) closedChan() chan error {
+	return t.closed
+}
+
+func (t *transportEvaluator) getVersion() (*internal.Semver, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.version != nil {
+		return t.version, nil
+	}
+	version, err := t.transport.PklVersion()
+	if err != nil {
+		return nil, err
+	}
+	semver, err := internal.ParseSemver(version)
+	if err != nil {
+		return nil, err
+	}
+	t.version = semver
+	return semver, nil
+}
+
+func (t *transportEvaluator) init() error {
+	session, err := t.connect()
+	if err != nil {
+		return err
+	}
+	t.mu.Lock()
+	t.session = session
+	t.mu.Unlock()
+	return nil
+}
+
+func (t *transportEvaluator) restart() error {
+	t.mu.Lock()
+	old := t.session
+	t.mu.Unlock()
+	if old != nil {
+		old.replaceOnce.Do(func() { close(old.replaced) })
+		_ = old.stream.Close()
+		<-old.done
+	}
+	return t.init()
+}
+
+// connect starts a new session, and starts exchanging messages over it.
+func (t *transportEvaluator) connect() (*transportSession, error) {
+	stream, err := t.transport.Connect()
+	if err != nil {
+		return nil, err
+	}
+	s := &transportSession{
+		stream:   stream,
+		done:     make(chan struct{}),
+		replaced: make(chan struct{}),
+	}
+	go t.readIncomingMessages(s)
+	go t.handleSendMessages(s)
+	return s, nil
+}
+
+// sessionLost notifies the evaluator manager that s has stopped working.
+//
+// Only the first call for a given session is reported, and nothing is reported if the
+// evaluator was closed explicitly, or if the session has since been replaced.
+func (t *transportEvaluator) sessionLost(s *transportSession, err error) {
+	s.lostOnce.Do(func() {
+		_ = s.stream.Close()
+		t.reportMu.RLock()
+		defer t.reportMu.RUnlock()
+		if t.exited.get() {
+			return
+		}
+		select {
+		case t.closed <- err:
+		case <-s.replaced:
+		case <-t.closing:
+		}
+	})
+}
+
+func (t *transportEvaluator) readIncomingMessages(s *transportSession) {
+	defer close(s.done)
+	dec := msgpack.NewDecoder(s.stream)
+	for {
+		msg, err := msgapi.Decode(dec)
+		if t.exited.get() {
+			return
+		}
+		if errors.Is(err, io.EOF) {
+			go t.sessionLost(s, &InternalError{err: errors.New("Pkl closed the connection")})
+			return
+		}
+		if err != nil {
+			go t.sessionLost(s, &InternalError{err: err})
+			return
+		}
+		internal.Debug("Received message: %#v", msg)
+		select {
+		case t.in <- msg:
+		case <-s.replaced:
+			return
+		case <-t.closing:
+			return
+		}
+	}
+}
+
+// handleSendMessages writes outgoing messages to the stream of s.
+//
+// Once s is lost, outgoing messages are dropped until s is replaced, so that the manager does not
+// block while it tears down its evaluators.
+func (t *transportEvaluator) handleSendMessages(s *transportSession) {
+	lost := s.done
+	for {
+		var msg msgapi.OutgoingMessage
+		select {
+		case <-s.replaced:
+			return
+		case m, ok := <-t.out:
+			if !ok {
+				return
+			}
+			msg = m
+		}
+		select {
+		case <-lost:
+			internal.Debug("Dropping message for a lost session: %#v", msg)
+			continue
+		default:
+		}
+		internal.Debug("Sending message: %#v", msg)
+		b, err := msg.ToMsgPack()
+		if err == nil {
+			_, err = s.stream.Write(b)
+		}
+		if err != nil {
+			go t.sessionLost(s, &InternalError{err: err})
+			closedLost := make(chan struct{})
+			close(closedLost)
+			lost = closedLost
+		}
+	}
+}
+
+func (t *transportEvaluator) deinit() error {
+	t.mu.Lock()
+	s := t.session
+	t.mu.Unlock()
+	if s == nil {
+		return nil
+	}
+	t.exited.set(true)
+	close(t.closing)
+	// the stream may already be closed if the session was lost.
+	_ = s.stream.Close()
+	// wait for the reader to stop before closing the channel that it writes to.
+	<-s.done
+	close(t.in)
+	close(t.out)
+	t.reportMu.Lock()
+	close(t.closed)
+	t.reportMu.Unlock()
+	return nil
+}
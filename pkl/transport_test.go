@@ -0,0 +1,54 @@
+//===----------------------------------------------------------------------===//
+// Copyright © 2026 Apple Inc. and the Pkl project authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//===----------------------------------------------------------------------===//
+
+package pkl_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/apple/pkl-go/pkl"
+	"github.com/apple/pkl-go/pkl/pkltest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewEvaluatorManagerWithTransport(t *testing.T) {
+	server := pkltest.NewServer(pkltest.WithPklVersion("0.29.0"))
+	defer server.Close()
+	server.HandleEvaluate(func(call *pkltest.Call) ([]byte, error) {
+		return pkltest.Encode(call.Expr)
+	})
+	manager := pkl.NewEvaluatorManagerWithTransport(server.Transport())
+	version, err := manager.GetVersion()
+	assert.NoError(t, err)
+	assert.Equal(t, "0.29.0", version)
+
+	ev, err := manager.NewEvaluator(context.Background())
+	if !assert.NoError(t, err) {
+		return
+	}
+	out, err := ev.EvaluateExpressionRaw(context.Background(), pkl.TextSource("foo = 1"), "foo")
+	assert.NoError(t, err)
+	assert.Equal(t, pkltest.MustEncode("foo"), out)
+
+	// without a supervisor, losing the session closes the manager.
+	server.Crash()
+	assert.Eventually(t, ev.Closed, time.Second, 10*time.Millisecond)
+	_, err = ev.EvaluateExpressionRaw(context.Background(), pkl.TextSource("foo = 1"), "foo")
+	assert.Error(t, err)
+	assert.NoError(t, manager.Close())
+}